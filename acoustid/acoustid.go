@@ -0,0 +1,189 @@
+// Package acoustid looks up the AcoustIDs of MusicBrainz recordings, so that
+// recordings sharing a fingerprint can be recognized as the same audio.
+package acoustid
+
+import (
+	"compress/gzip"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	mb2 "go.uploadedlobster.com/musicbrainzws2"
+)
+
+const (
+	DefaultServerURL string = "https://api.acoustid.org"
+	batchSize        int    = 100
+)
+
+// Lookup finds the AcoustIDs of MusicBrainz recordings.
+type Lookup interface {
+	// AcoustIDs returns the AcoustIDs of each of the recordings that has any.
+	AcoustIDs(recordings []mb2.MBID) (map[mb2.MBID][]string, error)
+}
+
+// DumpLookup reads AcoustIDs from a local CSV dump, optionally gzipped, with a
+// header naming an acoustid (or track_gid) column and an mbid column. Rows with
+// a disabled column set to true are skipped.
+type DumpLookup struct {
+	Path string
+}
+
+func NewDumpLookup(path string) DumpLookup {
+	return DumpLookup{Path: path}
+}
+
+func (d DumpLookup) AcoustIDs(recordings []mb2.MBID) (map[mb2.MBID][]string, error) {
+	ids := make(map[mb2.MBID][]string)
+	file, err := os.Open(d.Path)
+	if err != nil {
+		return ids, fmt.Errorf(`opening acoustid dump "%v": %w`, d.Path, err)
+	}
+	defer file.Close()
+	var r io.Reader = file
+	if strings.HasSuffix(d.Path, ".gz") {
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			return ids, fmt.Errorf(`opening acoustid dump "%v": %w`, d.Path, err)
+		}
+		defer gz.Close()
+		r = gz
+	}
+	return readDump(r, recordings)
+}
+
+// Scans the dump for the recordings, rather than loading it whole, as a full
+// dump holds tens of millions of rows.
+func readDump(r io.Reader, recordings []mb2.MBID) (map[mb2.MBID][]string, error) {
+	ids := make(map[mb2.MBID][]string)
+	wanted := make(map[mb2.MBID]bool)
+	for _, id := range recordings {
+		wanted[id] = true
+	}
+	reader := csv.NewReader(r)
+	reader.ReuseRecord = true
+	header, err := reader.Read()
+	if err != nil {
+		return ids, fmt.Errorf(`reading acoustid dump header: %w`, err)
+	}
+	idCol := slices.IndexFunc(header, func(col string) bool { return col == "acoustid" || col == "track_gid" })
+	mbidCol := slices.Index(header, "mbid")
+	disabledCol := slices.Index(header, "disabled")
+	if idCol < 0 || mbidCol < 0 {
+		return ids, fmt.Errorf(`acoustid dump header %v lacks acoustid and mbid columns`, header)
+	}
+	for {
+		row, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return ids, fmt.Errorf(`reading acoustid dump: %w`, err)
+		}
+		mbid := mb2.MBID(row[mbidCol])
+		if !wanted[mbid] {
+			continue
+		}
+		if disabledCol >= 0 && (row[disabledCol] == "t" || row[disabledCol] == "true") {
+			continue
+		}
+		if !slices.Contains(ids[mbid], row[idCol]) {
+			ids[mbid] = append(ids[mbid], row[idCol])
+		}
+	}
+	return ids, nil
+}
+
+// ServerLookup requests AcoustIDs from the AcoustID web service, or a server
+// standing in for it.
+type ServerLookup struct {
+	URL        string
+	ClientKey  string
+	HTTPClient *http.Client
+	// The wait between requests, as the web service is rate limited.
+	Interval time.Duration
+}
+
+func NewServerLookup(serverURL string, clientKey string) ServerLookup {
+	if serverURL == "" {
+		serverURL = DefaultServerURL
+	}
+	return ServerLookup{
+		URL:        strings.TrimRight(serverURL, "/"),
+		ClientKey:  clientKey,
+		HTTPClient: &http.Client{Timeout: 30 * time.Second},
+		Interval:   time.Second / 3,
+	}
+}
+
+func (s ServerLookup) AcoustIDs(recordings []mb2.MBID) (map[mb2.MBID][]string, error) {
+	ids := make(map[mb2.MBID][]string)
+	for start := 0; start < len(recordings); start += batchSize {
+		if start > 0 {
+			time.Sleep(s.Interval)
+		}
+		batch := recordings[start:min(start+batchSize, len(recordings))]
+		result, err := s.listByMBID(batch)
+		if err != nil {
+			return ids, err
+		}
+		for _, m := range result.MBIDs {
+			for _, t := range m.Tracks {
+				ids[m.MBID] = append(ids[m.MBID], t.ID)
+			}
+		}
+	}
+	return ids, nil
+}
+
+func (s ServerLookup) listByMBID(recordings []mb2.MBID) (listResult, error) {
+	var result listResult
+	query := url.Values{}
+	query.Set("format", "json")
+	query.Set("batch", "1")
+	if s.ClientKey != "" {
+		query.Set("client", s.ClientKey)
+	}
+	for _, id := range recordings {
+		query.Add("mbid", string(id))
+	}
+	req, err := http.NewRequest(http.MethodPost, s.URL+"/v2/track/list_by_mbid", strings.NewReader(query.Encode()))
+	if err != nil {
+		return result, fmt.Errorf(`building acoustid request: %w`, err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	resp, err := s.HTTPClient.Do(req)
+	if err != nil {
+		return result, fmt.Errorf(`request "%v" failed: %w`, req.URL, err)
+	}
+	defer resp.Body.Close()
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return result, fmt.Errorf(`request "%v" did not unmarshal cleanly: %w`, req.URL, err)
+	}
+	if result.Status != "ok" {
+		return result, fmt.Errorf(`request "%v" returned status %v: %v`, req.URL, resp.Status, result.Error.Message)
+	}
+	return result, nil
+}
+
+type listResult struct {
+	Status string `json:"status"`
+	Error  struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+	MBIDs []struct {
+		MBID   mb2.MBID `json:"mbid"`
+		Tracks []struct {
+			ID string `json:"id"`
+		} `json:"tracks"`
+	} `json:"mbids"`
+}
@@ -0,0 +1,95 @@
+package acoustid
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	mb2 "go.uploadedlobster.com/musicbrainzws2"
+)
+
+func TestReadDump(t *testing.T) {
+	dump := `id,acoustid,mbid,submission_count,disabled
+1,aaaa,rec1,3,f
+2,bbbb,rec1,1,f
+3,aaaa,rec2,5,f
+4,cccc,rec3,2,f
+5,dddd,rec2,1,t
+6,aaaa,rec1,1,f
+`
+	ids, err := readDump(strings.NewReader(dump), []mb2.MBID{"rec1", "rec2", "rec4"})
+	if err != nil {
+		t.Fatalf(`readDump returned error: %v`, err)
+	}
+	want := map[mb2.MBID][]string{
+		"rec1": {"aaaa", "bbbb"},
+		"rec2": {"aaaa"},
+	}
+	if !reflect.DeepEqual(ids, want) {
+		t.Errorf(`readDump = %v, wanted %v`, ids, want)
+	}
+
+	if _, err := readDump(strings.NewReader("id,mbid\n1,rec1\n"), nil); err == nil {
+		t.Error(`readDump on a dump without an acoustid column did not return an error`)
+	}
+}
+
+func TestServerLookup(t *testing.T) {
+	fingerprints := map[string][]string{
+		"rec1": {"aaaa"},
+		"rec2": {"aaaa", "bbbb"},
+	}
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.URL.Path != "/v2/track/list_by_mbid" || r.FormValue("client") != "key" {
+			json.NewEncoder(w).Encode(map[string]any{"status": "error", "error": map[string]any{"code": 4, "message": "invalid API key"}})
+			return
+		}
+		var result listResult
+		result.Status = "ok"
+		for _, mbid := range r.Form["mbid"] {
+			var m struct {
+				MBID   mb2.MBID `json:"mbid"`
+				Tracks []struct {
+					ID string `json:"id"`
+				} `json:"tracks"`
+			}
+			m.MBID = mb2.MBID(mbid)
+			for _, id := range fingerprints[mbid] {
+				m.Tracks = append(m.Tracks, struct {
+					ID string `json:"id"`
+				}{id})
+			}
+			result.MBIDs = append(result.MBIDs, m)
+		}
+		json.NewEncoder(w).Encode(result)
+	}))
+	defer server.Close()
+
+	recordings := make([]mb2.MBID, batchSize+1)
+	for i := range recordings {
+		recordings[i] = "unknown"
+	}
+	recordings[0], recordings[batchSize] = "rec1", "rec2"
+	lookup := NewServerLookup(server.URL+"/", "key")
+	lookup.Interval = 0
+	ids, err := lookup.AcoustIDs(recordings)
+	if err != nil {
+		t.Fatalf(`AcoustIDs returned error: %v`, err)
+	}
+	want := map[mb2.MBID][]string{"rec1": {"aaaa"}, "rec2": {"aaaa", "bbbb"}}
+	if !reflect.DeepEqual(ids, want) {
+		t.Errorf(`AcoustIDs = %v, wanted %v`, ids, want)
+	}
+	if requests != 2 {
+		t.Errorf(`AcoustIDs made %v requests, wanted 2 batches`, requests)
+	}
+
+	if _, err := NewServerLookup(server.URL, "wrong").AcoustIDs([]mb2.MBID{"rec1"}); err == nil {
+		t.Error(`AcoustIDs with an invalid key did not return an error`)
+	}
+}
@@ -15,6 +15,7 @@ import (
 const (
 	lengthLayout    string = "4:05"
 	trackFormatBase string = `{"id":%q,"title":%q,"length_str":%q,"position_str":%q}`
+	artistFormat    string = "$mb_albumartistid\t$albumartist\t$mb_artistid\t$artist"
 )
 
 var (
@@ -40,6 +41,38 @@ func ArtistTrackTitles(id mb2.MBID) ([]mb2.Track, error) {
 	return tracks, err
 }
 
+// LibraryArtists returns every distinct album artist and track artist in the
+// library that carries a MusicBrainz ID.
+func LibraryArtists() ([]mb2.Artist, error) {
+	var artists []mb2.Artist
+	if _, err := exec.LookPath("beet"); err != nil {
+		return artists, fmt.Errorf(`beet executable not found: %w`, err)
+	}
+	cmd := exec.Command("beet", "ls", "-f", artistFormat)
+	out, err := cmd.Output()
+	if err != nil {
+		return artists, fmt.Errorf(`command "%v" did not output cleanly: %w`, strings.Join(cmd.Args, " "), err)
+	}
+	return parseArtists(string(out)), nil
+}
+
+func parseArtists(beetStr string) []mb2.Artist {
+	var artists []mb2.Artist
+	seen := make(map[mb2.MBID]bool)
+	for _, line := range strings.Split(beetStr, "\n") {
+		fields := strings.Split(line, "\t")
+		for i := 0; i+1 < len(fields); i += 2 {
+			id := mb2.MBID(strings.TrimSpace(fields[i]))
+			if !id.IsValid() || seen[id] {
+				continue
+			}
+			seen[id] = true
+			artists = append(artists, mb2.Artist{ID: id, Name: strings.TrimSpace(fields[i+1])})
+		}
+	}
+	return artists
+}
+
 func unmarshalBeetsTracks(beetStr string) ([]mb2.Track, error) {
 	var tracks []mb2.Track
 	var combined error
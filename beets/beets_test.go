@@ -89,3 +89,25 @@ func TestUnmarshal(t *testing.T) {
 		}
 	}
 }
+
+func TestParseArtists(t *testing.T) {
+	in := strings.Join([]string{
+		"8682866a-4f7a-43f5-83b2-06eabd0f2d4c\tA\t8682866a-4f7a-43f5-83b2-06eabd0f2d4c\tA",
+		"8682866a-4f7a-43f5-83b2-06eabd0f2d4c\tA\tca1b4c5d-21bd-45aa-879f-60bbeb10e91e\tB",
+		"\tUntagged\t\tUntagged",
+		"",
+	}, "\n")
+	want := []mb2.Artist{
+		{ID: "8682866a-4f7a-43f5-83b2-06eabd0f2d4c", Name: "A"},
+		{ID: "ca1b4c5d-21bd-45aa-879f-60bbeb10e91e", Name: "B"},
+	}
+	out := parseArtists(in)
+	if len(out) != len(want) {
+		t.Fatalf(`parseArtists(%q) = %+v, wanted %+v`, in, out, want)
+	}
+	for i := range want {
+		if out[i].ID != want[i].ID || out[i].Name != want[i].Name {
+			t.Errorf(`parseArtists(%q) = %+v, wanted %+v`, in, out, want)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"fmt"
+	"log/slog"
+	"slices"
+
+	"github.com/frigorific44/musicgreed/config"
+	"github.com/spf13/cobra"
+)
+
+// Loads the configuration file and the name of the selected profile.
+func loadConfig(cmd *cobra.Command) (config.Config, string, error) {
+	profile, _ := cmd.Flags().GetString("profile")
+	path, _ := cmd.Flags().GetString("config")
+	if path == "" {
+		var err error
+		if path, err = config.DefaultPath(); err != nil {
+			return config.Config{}, profile, err
+		}
+	}
+	conf, err := config.Load(path)
+	return conf, profile, err
+}
+
+// The alternate and not-alternate terms configured for the selected profile,
+// to extend the default term groups with. Resolved along with the flags by
+// applyConfig, and left nil when empty to compare equal to flags saved
+// without them.
+var configAltTerms, configNotAltTerms map[string][]string
+
+// Sets flags not given on the command line from the configuration file's
+// defaults and the selected profile, and resolves the configured term groups.
+func applyConfig(cmd *cobra.Command) error {
+	conf, profile, err := loadConfig(cmd)
+	if err != nil {
+		return err
+	}
+	values, err := conf.Resolve(profile)
+	if err != nil {
+		return err
+	}
+	for name, value := range values {
+		flag := cmd.Flags().Lookup(name)
+		if flag == nil {
+			if !anyCommandFlag(cmd.Root(), name) {
+				fmt.Fprintf(cmd.ErrOrStderr(), "Configured flag %q is not a flag of any command, so it is ignored\n", name)
+				continue
+			}
+			slog.Debug(
+				"configured flag not available to command",
+				"flag", name,
+				"command", cmd.Name())
+			continue
+		}
+		if name == "config" || name == "profile" {
+			return fmt.Errorf(`flag %q selects the configuration, so can't be configured`, name)
+		}
+		if flag.Changed {
+			continue
+		}
+		if err := cmd.Flags().Set(name, value); err != nil {
+			return fmt.Errorf(`configured value %q for flag %q: %w`, value, name, err)
+		}
+	}
+	configAltTerms, configNotAltTerms = conf.TermGroups(profile)
+	if len(configAltTerms) == 0 {
+		configAltTerms = nil
+	}
+	if len(configNotAltTerms) == 0 {
+		configNotAltTerms = nil
+	}
+	return nil
+}
+
+// Whether the command or any of its subcommands has the named flag.
+func anyCommandFlag(cmd *cobra.Command, name string) bool {
+	if cmd.Flags().Lookup(name) != nil || cmd.PersistentFlags().Lookup(name) != nil {
+		return true
+	}
+	return slices.ContainsFunc(cmd.Commands(), func(sub *cobra.Command) bool {
+		return anyCommandFlag(sub, name)
+	})
+}
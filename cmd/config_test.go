@@ -0,0 +1,21 @@
+package cmd
+
+import "testing"
+
+func TestAnyCommandFlag(t *testing.T) {
+	root := NewRootCmd()
+	cases := []struct {
+		Name string
+		Want bool
+	}{
+		{"output", true},
+		{"dformat", true},
+		{"state", true},
+		{"dformats", false},
+	}
+	for _, c := range cases {
+		if got := anyCommandFlag(root, c.Name); got != c.Want {
+			t.Errorf(`anyCommandFlag(root, %q) = %v, wanted %v`, c.Name, got, c.Want)
+		}
+	}
+}
@@ -0,0 +1,251 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/frigorific44/musicgreed/musicinfo"
+	"github.com/spf13/cobra"
+	mb2 "go.uploadedlobster.com/musicbrainzws2"
+)
+
+// diffCmd represents the diff command
+func NewDiffCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   `diff previous.json [current.json]`,
+		Short: "Compare a saved set cover result with a fresh one.",
+		Long: "This command compares the JSON result of a previous `setcover` run with a " +
+			"fresh one, computed for the same artists with the same flags, and reports the " +
+			"songs and releases added to or removed from MusicBrainz since, the releases " +
+			"whose tracklists changed, and whether the recommended set cover changed. To " +
+			"save a result to compare against later:" +
+			"\n\n`musicgreed setcover --format=json artist > previous.json`" +
+			"\n\n`musicgreed diff previous.json`" +
+			"\n\nKeys left out of the saved flags, such as the Jellyfin and AcoustID keys, are " +
+			"taken from their environment variables. Two saved results may also be compared " +
+			"without computing a fresh one:" +
+			"\n\n`musicgreed diff previous.json current.json`",
+		Args: cobra.RangeArgs(1, 2),
+		Run: func(cmd *cobra.Command, args []string) {
+			format, err := outputFormat(cmd)
+			if err != nil {
+				fmt.Println(err)
+				return
+			}
+			previous, err := readSetCoverResult(args[0])
+			if err != nil {
+				fmt.Fprintln(messages, err)
+				return
+			}
+
+			var current setCoverResult
+			if len(args) == 2 {
+				if current, err = readSetCoverResult(args[1]); err != nil {
+					fmt.Fprintln(messages, err)
+					return
+				}
+			} else {
+				// Every minimal cover is kept, so the previously recommended one is
+				// found among them even when no longer ranked within the saved --top.
+				flags := previous.Flags
+				flags.Top = 0
+				library, err := openLibrary(flags)
+				if err != nil {
+					fmt.Fprintln(messages, err)
+					return
+				}
+				scc := setCoverConfig{setCoverFlags: flags, ArtistMBIDs: previous.Artists, MusicLibrary: library}
+				client, stop := musicinfo.NewMGClient()
+				defer stop()
+				groups, releases, covers, err := computeSetCovers(client, &scc)
+				if err != nil {
+					fmt.Fprintln(messages, err)
+					return
+				}
+				current = newSetCoverResult(releases, covers, scc)
+				if scc.Remainder {
+					current.Ownership = ownership(groups, scc)
+				}
+			}
+
+			diff := diffSetCoverResults(previous, current)
+			if format == formatJSON {
+				if err := writeJSON(diff); err != nil {
+					fmt.Fprintln(messages, err)
+				}
+				return
+			}
+			printSetCoverDiff(diff)
+		},
+	}
+
+	addOutputFlags(cmd)
+
+	return cmd
+}
+
+// The changes from one set cover result to another.
+type setCoverDiff struct {
+	NewSongs        []string        `json:"newSongs"`
+	RemovedSongs    []string        `json:"removedSongs"`
+	NewReleases     []releaseRef    `json:"newReleases"`
+	RemovedReleases []releaseRef    `json:"removedReleases"`
+	ChangedReleases []releaseChange `json:"changedReleases"`
+	// Whether the previously recommended cover, ranked first, is no longer
+	// one of the minimal covers.
+	CoverChanged  bool         `json:"coverChanged"`
+	PreviousCover []releaseRef `json:"previousCover"`
+	CurrentCover  []releaseRef `json:"currentCover"`
+}
+
+// A release whose tracklist changed.
+type releaseChange struct {
+	Title   string   `json:"title"`
+	ID      mb2.MBID `json:"id"`
+	Added   []string `json:"added"`
+	Removed []string `json:"removed"`
+}
+
+func readSetCoverResult(path string) (setCoverResult, error) {
+	var result setCoverResult
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return result, fmt.Errorf(`reading set cover result "%v": %w`, path, err)
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return result, fmt.Errorf(`set cover result "%v" did not unmarshal cleanly: %w`, path, err)
+	}
+	return result, nil
+}
+
+func diffSetCoverResults(previous, current setCoverResult) setCoverDiff {
+	diff := setCoverDiff{
+		NewReleases:     []releaseRef{},
+		RemovedReleases: []releaseRef{},
+		ChangedReleases: []releaseChange{},
+	}
+	diff.NewSongs, diff.RemovedSongs = diffTitles(previous.Songs, current.Songs)
+
+	previousReleases := make(map[mb2.MBID]releaseResult)
+	for _, r := range previous.Releases {
+		previousReleases[r.ID] = r
+	}
+	currentReleases := make(map[mb2.MBID]bool)
+	for _, r := range current.Releases {
+		currentReleases[r.ID] = true
+		p, ok := previousReleases[r.ID]
+		if !ok {
+			diff.NewReleases = append(diff.NewReleases, releaseRef{Title: r.Title, ID: r.ID})
+			continue
+		}
+		if added, removed := diffTitles(p.Tracks, r.Tracks); len(added) > 0 || len(removed) > 0 {
+			diff.ChangedReleases = append(diff.ChangedReleases, releaseChange{Title: r.Title, ID: r.ID, Added: added, Removed: removed})
+		}
+	}
+	for _, r := range previous.Releases {
+		if !currentReleases[r.ID] {
+			diff.RemovedReleases = append(diff.RemovedReleases, releaseRef{Title: r.Title, ID: r.ID})
+		}
+	}
+	slices.SortFunc(diff.NewReleases, compareReleaseRefs)
+	slices.SortFunc(diff.RemovedReleases, compareReleaseRefs)
+	slices.SortFunc(diff.ChangedReleases, func(a, b releaseChange) int {
+		return compareReleaseRefs(releaseRef{Title: a.Title, ID: a.ID}, releaseRef{Title: b.Title, ID: b.ID})
+	})
+
+	diff.PreviousCover = recommendedCover(previous)
+	diff.CurrentCover = recommendedCover(current)
+	if len(previous.Covers) == 0 {
+		diff.CoverChanged = len(current.Covers) > 0
+	} else {
+		diff.CoverChanged = !slices.ContainsFunc(current.Covers, func(cover []coverContribution) bool {
+			return sameCover(cover, previous.Covers[0])
+		})
+	}
+	return diff
+}
+
+func sameCover(a, b []coverContribution) bool {
+	ids := func(cover []coverContribution) []mb2.MBID {
+		var result []mb2.MBID
+		for _, c := range cover {
+			result = append(result, c.ID)
+		}
+		slices.Sort(result)
+		return result
+	}
+	return slices.Equal(ids(a), ids(b))
+}
+
+// Returns the titles only in current, and those only in previous.
+func diffTitles(previous, current []string) ([]string, []string) {
+	added, removed := []string{}, []string{}
+	for _, t := range current {
+		if !slices.Contains(previous, t) && !slices.Contains(added, t) {
+			added = append(added, t)
+		}
+	}
+	for _, t := range previous {
+		if !slices.Contains(current, t) && !slices.Contains(removed, t) {
+			removed = append(removed, t)
+		}
+	}
+	slices.Sort(added)
+	slices.Sort(removed)
+	return added, removed
+}
+
+// Returns the releases of the first ranked cover, ordered by title.
+func recommendedCover(result setCoverResult) []releaseRef {
+	cover := []releaseRef{}
+	if len(result.Covers) > 0 {
+		for _, c := range result.Covers[0] {
+			cover = append(cover, releaseRef{Title: c.Title, ID: c.ID})
+		}
+	}
+	slices.SortFunc(cover, compareReleaseRefs)
+	return cover
+}
+
+func printSetCoverDiff(diff setCoverDiff) {
+	printDiffList := func(label string, items []string) {
+		if len(items) > 0 {
+			fmt.Printf("\n%v (%v):\n", label, len(items))
+			fmt.Println(strings.Join(items, "; "))
+		}
+	}
+	refTitles := func(refs []releaseRef) []string {
+		var titles []string
+		for _, r := range refs {
+			titles = append(titles, r.Title)
+		}
+		return titles
+	}
+	printDiffList("New Songs", diff.NewSongs)
+	printDiffList("Removed Songs", diff.RemovedSongs)
+	printDiffList("New Releases", refTitles(diff.NewReleases))
+	printDiffList("Removed Releases", refTitles(diff.RemovedReleases))
+	if len(diff.ChangedReleases) > 0 {
+		fmt.Printf("\nChanged Releases (%v):\n", len(diff.ChangedReleases))
+		for _, c := range diff.ChangedReleases {
+			var changes []string
+			for _, t := range c.Added {
+				changes = append(changes, "+"+t)
+			}
+			for _, t := range c.Removed {
+				changes = append(changes, "-"+t)
+			}
+			fmt.Printf("%v: %v\n", c.Title, strings.Join(changes, "; "))
+		}
+	}
+	if diff.CoverChanged {
+		fmt.Println("\nThe recommended set cover changed:")
+		fmt.Println("Previous:", strings.Join(refTitles(diff.PreviousCover), "; "))
+		fmt.Println("Current: ", strings.Join(refTitles(diff.CurrentCover), "; "))
+	} else {
+		fmt.Println("\nThe recommended set cover is unchanged.")
+	}
+}
@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestDiffSetCoverResults(t *testing.T) {
+	previous := setCoverResult{
+		Songs: []string{"a", "b", "c"},
+		Releases: []releaseResult{
+			{ID: "one", Title: "One", Tracks: []string{"a", "b"}},
+			{ID: "two", Title: "Two", Tracks: []string{"c"}},
+			{ID: "gone", Title: "Gone", Tracks: []string{"a"}},
+		},
+		Covers: [][]coverContribution{{{ID: "one", Title: "One"}, {ID: "two", Title: "Two"}}},
+	}
+	current := setCoverResult{
+		Songs: []string{"a", "b", "d"},
+		Releases: []releaseResult{
+			{ID: "one", Title: "One", Tracks: []string{"a", "b"}},
+			{ID: "two", Title: "Two", Tracks: []string{"d"}},
+			{ID: "new", Title: "New", Tracks: []string{"a", "b", "d"}},
+		},
+		Covers: [][]coverContribution{{{ID: "new", Title: "New"}}},
+	}
+	diff := diffSetCoverResults(previous, current)
+	if !slices.Equal(diff.NewSongs, []string{"d"}) || !slices.Equal(diff.RemovedSongs, []string{"c"}) {
+		t.Errorf(`diffSetCoverResults songs = +%v -%v, wanted +[d] -[c]`, diff.NewSongs, diff.RemovedSongs)
+	}
+	if len(diff.NewReleases) != 1 || diff.NewReleases[0].ID != "new" {
+		t.Errorf(`diffSetCoverResults new releases = %v, wanted [new]`, diff.NewReleases)
+	}
+	if len(diff.RemovedReleases) != 1 || diff.RemovedReleases[0].ID != "gone" {
+		t.Errorf(`diffSetCoverResults removed releases = %v, wanted [gone]`, diff.RemovedReleases)
+	}
+	if len(diff.ChangedReleases) != 1 || diff.ChangedReleases[0].ID != "two" ||
+		!slices.Equal(diff.ChangedReleases[0].Added, []string{"d"}) || !slices.Equal(diff.ChangedReleases[0].Removed, []string{"c"}) {
+		t.Errorf(`diffSetCoverResults changed releases = %+v, wanted two with +[d] -[c]`, diff.ChangedReleases)
+	}
+	if !diff.CoverChanged {
+		t.Error(`diffSetCoverResults did not report the changed cover`)
+	}
+
+	// A previous cover still among tied minimal covers is unchanged, whichever is first.
+	current.Covers = [][]coverContribution{
+		{{ID: "three"}, {ID: "one"}},
+		{{ID: "two"}, {ID: "one"}},
+	}
+	if diff := diffSetCoverResults(previous, current); diff.CoverChanged {
+		t.Error(`diffSetCoverResults reported a change for a cover still minimal`)
+	}
+	if diff := diffSetCoverResults(previous, previous); diff.CoverChanged || len(diff.NewSongs) > 0 ||
+		len(diff.ChangedReleases) > 0 || !slices.Equal(diff.CurrentCover, []releaseRef{{Title: "One", ID: "one"}, {Title: "Two", ID: "two"}}) {
+		t.Errorf(`diffSetCoverResults of a result with itself = %+v, wanted no changes`, diff)
+	}
+}
@@ -0,0 +1,128 @@
+package cmd
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+
+	mb2 "go.uploadedlobster.com/musicbrainzws2"
+)
+
+// Why a release was chosen for the minimal set covers it is part of.
+type releaseExplanation struct {
+	Title string   `json:"title"`
+	ID    mb2.MBID `json:"id"`
+	// The number of covers the release is part of.
+	Covers int `json:"covers"`
+	// Tracks found on no other release.
+	Essential []string `json:"essential"`
+	// Releases that could take its place in one of its covers.
+	Substitutes []releaseRef `json:"substitutes"`
+}
+
+// A release referred to by title and MBID.
+type releaseRef struct {
+	Title string   `json:"title"`
+	ID    mb2.MBID `json:"id"`
+}
+
+// Explains each release chosen in the covers, ordered by the number of covers
+// it is part of.
+func explainCovers(releases []mb2.Release, covers [][]mb2.Release, scc setCoverConfig) []releaseExplanation {
+	trackMap := buildTrackMap(releases, scc)
+	carried := make([]map[string]bool, len(releases))
+	for i := range carried {
+		carried[i] = make(map[string]bool)
+	}
+	for t, rs := range trackMap {
+		for _, i := range rs {
+			carried[i][t] = true
+		}
+	}
+	index := make(map[mb2.MBID]int, len(releases))
+	for i, r := range releases {
+		index[r.ID] = i
+	}
+
+	explained := make(map[int]*releaseExplanation)
+	substituted := make(map[int]map[int]bool)
+	for _, cover := range covers {
+		chosen := make(map[int]bool, len(cover))
+		for _, r := range cover {
+			chosen[index[r.ID]] = true
+		}
+		for i := range chosen {
+			e, ok := explained[i]
+			if !ok {
+				e = &releaseExplanation{
+					Title:       releases[i].Title,
+					ID:          releases[i].ID,
+					Essential:   []string{},
+					Substitutes: []releaseRef{},
+				}
+				for t := range carried[i] {
+					if len(trackMap[t]) == 1 {
+						e.Essential = append(e.Essential, t)
+					}
+				}
+				slices.Sort(e.Essential)
+				explained[i] = e
+				substituted[i] = make(map[int]bool)
+			}
+			e.Covers += 1
+			// The slot the release fills holds the tracks no other chosen release carries.
+			var slot []string
+			for t := range carried[i] {
+				if !slices.ContainsFunc(trackMap[t], func(j int) bool { return j != i && chosen[j] }) {
+					slot = append(slot, t)
+				}
+			}
+			for j := range releases {
+				if chosen[j] || substituted[i][j] {
+					continue
+				}
+				if !slices.ContainsFunc(slot, func(t string) bool { return !carried[j][t] }) {
+					substituted[i][j] = true
+					e.Substitutes = append(e.Substitutes, releaseRef{Title: releases[j].Title, ID: releases[j].ID})
+				}
+			}
+		}
+	}
+
+	var explanations []releaseExplanation
+	for _, e := range explained {
+		slices.SortFunc(e.Substitutes, func(a, b releaseRef) int {
+			return cmp.Or(cmp.Compare(a.Title, b.Title), cmp.Compare(a.ID, b.ID))
+		})
+		explanations = append(explanations, *e)
+	}
+	slices.SortFunc(explanations, func(a, b releaseExplanation) int {
+		return cmp.Or(-cmp.Compare(a.Covers, b.Covers), cmp.Compare(a.Title, b.Title), cmp.Compare(a.ID, b.ID))
+	})
+	return explanations
+}
+
+func printExplanations(explanations []releaseExplanation, covers int) {
+	if len(explanations) == 0 {
+		return
+	}
+	fmt.Println("\nExplanations")
+	fmt.Println(horizontal)
+	for _, e := range explanations {
+		fmt.Printf("%v, in %v of %v covers\n", e.Title, e.Covers, covers)
+		if len(e.Essential) > 0 {
+			fmt.Println("  Essential:", strings.Join(e.Essential, "; "))
+		}
+		if len(e.Substitutes) > 0 {
+			var titles []string
+			for _, s := range e.Substitutes {
+				titles = append(titles, s.Title)
+			}
+			fmt.Println("  Substitutes:", strings.Join(titles, "; "))
+		}
+		if len(e.Essential) == 0 && len(e.Substitutes) == 0 {
+			fmt.Println("  No other single release carries the tracks it covers")
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"slices"
+	"testing"
+
+	mb2 "go.uploadedlobster.com/musicbrainzws2"
+)
+
+func TestExplainCovers(t *testing.T) {
+	releases := []mb2.Release{
+		testRelease("one", "a", "b"),
+		testRelease("two", "b", "c"),
+		testRelease("three", "c", "d"),
+		testRelease("four", "d"),
+		testRelease("five", "c", "d"),
+		// A title repeated on one release is still only carried by it.
+		testRelease("six", "e", "e"),
+	}
+	scc := setCoverConfig{}
+	covers := setcovers(releases, scc)
+	if len(covers) != 2 {
+		t.Fatalf(`setcovers(%v) = %v, wanted two covers`, releases, covers)
+	}
+
+	type explanation struct {
+		Covers      int
+		Essential   []string
+		Substitutes []mb2.MBID
+	}
+	want := map[mb2.MBID]explanation{
+		"one":   {Covers: 2, Essential: []string{"a"}},
+		"six":   {Covers: 2, Essential: []string{"e"}},
+		"five":  {Covers: 1, Substitutes: []mb2.MBID{"three"}},
+		"three": {Covers: 1, Substitutes: []mb2.MBID{"five"}},
+	}
+	res := explainCovers(releases, covers, scc)
+	if len(res) != len(want) || res[0].ID != "one" {
+		t.Fatalf(`explainCovers(%v) = %+v, wanted "one" first of %v explanations`, covers, res, len(want))
+	}
+	for _, e := range res {
+		var substitutes []mb2.MBID
+		for _, s := range e.Substitutes {
+			substitutes = append(substitutes, s.ID)
+		}
+		w := want[e.ID]
+		if e.Covers != w.Covers || !slices.Equal(e.Essential, w.Essential) ||
+			!slices.Equal(substitutes, w.Substitutes) {
+			t.Errorf(`explainCovers(%v) explained %v as %+v, wanted %+v`, covers, e.ID, e, w)
+		}
+	}
+}
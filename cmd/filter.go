@@ -0,0 +1,372 @@
+package cmd
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/frigorific44/musicgreed/expr"
+	mb2 "go.uploadedlobster.com/musicbrainzws2"
+)
+
+type groupFilter struct {
+	Name   string
+	Filter func([]mb2.ReleaseGroup, setCoverConfig) []mb2.ReleaseGroup
+	Active func(setCoverConfig) bool
+}
+
+var groupFilters = []groupFilter{
+	{
+		Name:   "--dsec",
+		Filter: filterBySecondaryType,
+		Active: func(scc setCoverConfig) bool { return len(scc.DSec) > 0 },
+	},
+	{
+		Name:   "--primary/--dprimary",
+		Filter: filterByPrimaryType,
+		Active: func(scc setCoverConfig) bool { return len(scc.Primary) > 0 || len(scc.DPrimary) > 0 },
+	},
+}
+
+type releaseFilter struct {
+	Name   string
+	Keep   func(mb2.Release, setCoverConfig) bool
+	Active func(setCoverConfig) bool
+}
+
+var releaseFilters = []releaseFilter{
+	{
+		Name: "--status",
+		Keep: func(r mb2.Release, scc setCoverConfig) bool {
+			return containsFold(scc.Status, r.Status)
+		},
+		Active: func(scc setCoverConfig) bool { return len(scc.Status) > 0 },
+	},
+	{
+		Name: "--dformat",
+		Keep: func(r mb2.Release, scc setCoverConfig) bool {
+			for _, m := range r.Media {
+				if !containsFold(scc.DFormat, m.Format) {
+					return true
+				}
+			}
+			return len(r.Media) == 0
+		},
+		Active: func(scc setCoverConfig) bool { return len(scc.DFormat) > 0 },
+	},
+	{
+		Name: "--country",
+		Keep: func(r mb2.Release, scc setCoverConfig) bool {
+			if containsFold(scc.Country, r.Country) {
+				return true
+			}
+			for _, e := range r.ReleaseEvents {
+				if e.Area == nil {
+					continue
+				}
+				if containsFold(scc.Country, e.Area.Name) || slices.ContainsFunc(e.Area.ISO31661Codes, func(code string) bool {
+					return containsFold(scc.Country, code)
+				}) {
+					return true
+				}
+			}
+			return false
+		},
+		Active: func(scc setCoverConfig) bool { return len(scc.Country) > 0 },
+	},
+	{
+		Name: "--since/--until",
+		Keep: func(r mb2.Release, scc setCoverConfig) bool {
+			if r.Date.IsZero() {
+				return false
+			}
+			return (scc.Since == "" || !r.Date.Time.Before(scc.SinceDate)) && (scc.Until == "" || r.Date.Time.Before(scc.UntilDate))
+		},
+		Active: func(scc setCoverConfig) bool { return scc.Since != "" || scc.Until != "" },
+	},
+	{
+		Name: "--dpackaging",
+		Keep: func(r mb2.Release, scc setCoverConfig) bool {
+			return !containsFold(scc.DPackaging, r.Packaging)
+		},
+		Active: func(scc setCoverConfig) bool { return len(scc.DPackaging) > 0 },
+	},
+}
+
+// The release statuses of MusicBrainz, see https://musicbrainz.org/doc/Release#Status.
+var releaseStatuses = []string{"official", "promotion", "bootleg", "pseudo-release"}
+
+func validateReleaseFilters(scc setCoverConfig) error {
+	for _, s := range scc.Status {
+		if !containsFold(releaseStatuses, s) {
+			return fmt.Errorf(`invalid --status %q: must be one of %v`, s, strings.Join(releaseStatuses, ", "))
+		}
+	}
+	if _, err := parsePartialDate(scc.Since, false); err != nil {
+		return fmt.Errorf(`invalid --since date: %w`, err)
+	}
+	if _, err := parsePartialDate(scc.Until, true); err != nil {
+		return fmt.Errorf(`invalid --until date: %w`, err)
+	}
+	if _, err := parseFilterExpression(scc.Filter); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Parses a date of year, month, or day precision. As the end of a range, the
+// returned time is the start of the following period, to compare exclusively.
+func parsePartialDate(date string, end bool) (time.Time, error) {
+	if date == "" {
+		return time.Time{}, nil
+	}
+	for _, layout := range []struct {
+		Format string
+		Years  int
+		Months int
+		Days   int
+	}{
+		{"2006", 1, 0, 0},
+		{"2006-01", 0, 1, 0},
+		{"2006-01-02", 0, 0, 1},
+	} {
+		if t, err := time.Parse(layout.Format, date); err == nil {
+			if end {
+				t = t.AddDate(layout.Years, layout.Months, layout.Days)
+			}
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf(`%q is not formatted as YYYY, YYYY-MM, or YYYY-MM-DD`, date)
+}
+
+// Applies each active release filter in turn, printing how many releases each
+// removed. Release groups left without releases are removed.
+func filterReleases(groups []mb2.ReleaseGroup, scc setCoverConfig) []mb2.ReleaseGroup {
+	// Parsed once for every release, having been validated beforehand.
+	scc.SinceDate, _ = parsePartialDate(scc.Since, false)
+	scc.UntilDate, _ = parsePartialDate(scc.Until, true)
+	var summary []string
+	for _, rf := range releaseFilters {
+		if !rf.Active(scc) {
+			continue
+		}
+		var removed int
+		var filtered []mb2.ReleaseGroup
+		for _, rg := range groups {
+			releases := slices.DeleteFunc(slices.Clone(rg.Releases), func(r mb2.Release) bool {
+				return !rf.Keep(r, scc)
+			})
+			removed += len(rg.Releases) - len(releases)
+			if len(releases) > 0 {
+				rg.Releases = releases
+				filtered = append(filtered, rg)
+			}
+		}
+		groups = filtered
+		summary = append(summary, fmt.Sprintf("%v removed %v", rf.Name, removed))
+	}
+	if len(summary) > 0 {
+		fmt.Fprintln(messages, "Releases filtered:", strings.Join(summary, ", "))
+	}
+	return groups
+}
+
+// The fields available to filter expressions, evaluated for each track.
+var expressionFields = map[string]func(rg mb2.ReleaseGroup, r mb2.Release, t mb2.Track) expr.Value{
+	"group.title":            func(rg mb2.ReleaseGroup, r mb2.Release, t mb2.Track) expr.Value { return rg.Title },
+	"group.primary":          func(rg mb2.ReleaseGroup, r mb2.Release, t mb2.Track) expr.Value { return rg.PrimaryType },
+	"group.secondary":        func(rg mb2.ReleaseGroup, r mb2.Release, t mb2.Track) expr.Value { return stringList(rg.SecondaryTypes) },
+	"release.id":             func(rg mb2.ReleaseGroup, r mb2.Release, t mb2.Track) expr.Value { return string(r.ID) },
+	"release.title":          func(rg mb2.ReleaseGroup, r mb2.Release, t mb2.Track) expr.Value { return r.Title },
+	"release.disambiguation": func(rg mb2.ReleaseGroup, r mb2.Release, t mb2.Track) expr.Value { return r.Disambiguation },
+	"release.status":         func(rg mb2.ReleaseGroup, r mb2.Release, t mb2.Track) expr.Value { return r.Status },
+	"release.country":        func(rg mb2.ReleaseGroup, r mb2.Release, t mb2.Track) expr.Value { return r.Country },
+	"release.packaging":      func(rg mb2.ReleaseGroup, r mb2.Release, t mb2.Track) expr.Value { return r.Packaging },
+	"release.barcode":        func(rg mb2.ReleaseGroup, r mb2.Release, t mb2.Track) expr.Value { return r.Barcode },
+	"release.artist":         func(rg mb2.ReleaseGroup, r mb2.Release, t mb2.Track) expr.Value { return creditString(r.ArtistCredit) },
+	"release.date": func(rg mb2.ReleaseGroup, r mb2.Release, t mb2.Track) expr.Value {
+		if r.Date.IsZero() {
+			return ""
+		}
+		return r.Date.Time.Format("2006-01-02")
+	},
+	"release.year": func(rg mb2.ReleaseGroup, r mb2.Release, t mb2.Track) expr.Value {
+		if r.Date.IsZero() {
+			return float64(0)
+		}
+		return float64(r.Date.Time.Year())
+	},
+	"release.formats": func(rg mb2.ReleaseGroup, r mb2.Release, t mb2.Track) expr.Value {
+		var formats []string
+		for _, m := range r.Media {
+			formats = append(formats, m.Format)
+		}
+		return stringList(formats)
+	},
+	"track.title":    func(rg mb2.ReleaseGroup, r mb2.Release, t mb2.Track) expr.Value { return t.Title },
+	"track.number":   func(rg mb2.ReleaseGroup, r mb2.Release, t mb2.Track) expr.Value { return t.Number },
+	"track.position": func(rg mb2.ReleaseGroup, r mb2.Release, t mb2.Track) expr.Value { return float64(t.Position) },
+	"track.length":   func(rg mb2.ReleaseGroup, r mb2.Release, t mb2.Track) expr.Value { return t.Length.Seconds() },
+	"track.artist": func(rg mb2.ReleaseGroup, r mb2.Release, t mb2.Track) expr.Value {
+		return creditString(trackCredit(r, t))
+	},
+	"track.video": func(rg mb2.ReleaseGroup, r mb2.Release, t mb2.Track) expr.Value { return t.Recording.IsVideo },
+	"track.recording": func(rg mb2.ReleaseGroup, r mb2.Release, t mb2.Track) expr.Value {
+		return string(t.Recording.ID)
+	},
+}
+
+type trackEnv struct {
+	Group   mb2.ReleaseGroup
+	Release mb2.Release
+	Track   mb2.Track
+}
+
+func (env trackEnv) Lookup(field string) (expr.Value, bool) {
+	getter, ok := expressionFields[field]
+	if !ok {
+		return nil, false
+	}
+	return getter(env.Group, env.Release, env.Track), true
+}
+
+func stringList(list []string) []expr.Value {
+	values := make([]expr.Value, len(list))
+	for i, s := range list {
+		values[i] = s
+	}
+	return values
+}
+
+func creditString(credit mb2.ArtistCredit) string {
+	var sb strings.Builder
+	for _, name := range credit {
+		sb.WriteString(name.Name)
+		sb.WriteString(name.JoinPhrase)
+	}
+	return sb.String()
+}
+
+// Parses the filter expression, ensuring it only references known fields.
+func parseFilterExpression(src string) (*expr.Expr, error) {
+	if strings.TrimSpace(src) == "" {
+		return nil, nil
+	}
+	e, err := expr.Parse(src)
+	if err != nil {
+		return nil, fmt.Errorf(`invalid --filter expression: %w`, err)
+	}
+	for _, field := range e.Fields() {
+		if _, ok := expressionFields[field]; !ok {
+			known := make([]string, 0, len(expressionFields))
+			for k := range expressionFields {
+				known = append(known, k)
+			}
+			slices.Sort(known)
+			return nil, fmt.Errorf(`invalid --filter expression: unknown field %q, expected one of %v`, field, strings.Join(known, ", "))
+		}
+	}
+	return e, nil
+}
+
+// Keeps only the tracks for which the filter expression holds. Releases left
+// without tracks, and release groups left without releases, are removed.
+func filterByExpression(groups []mb2.ReleaseGroup, scc setCoverConfig) ([]mb2.ReleaseGroup, error) {
+	e, err := parseFilterExpression(scc.Filter)
+	if err != nil || e == nil {
+		return groups, err
+	}
+	var removedTracks, removedReleases int
+	var filtered []mb2.ReleaseGroup
+	for _, rg := range groups {
+		var releases []mb2.Release
+		for _, r := range rg.Releases {
+			var media []mb2.Medium
+			for _, m := range r.Media {
+				var tracks []mb2.Track
+				for _, t := range m.Tracks {
+					keep, err := e.Eval(trackEnv{Group: rg, Release: r, Track: t})
+					if err != nil {
+						return nil, fmt.Errorf(`evaluating --filter expression: %w`, err)
+					}
+					if keep {
+						tracks = append(tracks, t)
+					} else {
+						removedTracks += 1
+					}
+				}
+				if len(tracks) > 0 {
+					m.Tracks = tracks
+					media = append(media, m)
+				}
+			}
+			if len(media) > 0 {
+				r.Media = media
+				releases = append(releases, r)
+			} else {
+				removedReleases += 1
+			}
+		}
+		if len(releases) > 0 {
+			rg.Releases = releases
+			filtered = append(filtered, rg)
+		}
+	}
+	fmt.Fprintf(messages, "Filter expression removed %v tracks and %v releases\n", removedTracks, removedReleases)
+	return filtered, nil
+}
+
+// Applies each active release group filter in turn, printing how many release groups each removed.
+func filterReleaseGroups(groups []mb2.ReleaseGroup, scc setCoverConfig) []mb2.ReleaseGroup {
+	var summary []string
+	for _, gf := range groupFilters {
+		if !gf.Active(scc) {
+			continue
+		}
+		before := len(groups)
+		groups = gf.Filter(groups, scc)
+		summary = append(summary, fmt.Sprintf("%v removed %v", gf.Name, before-len(groups)))
+	}
+	if len(summary) > 0 {
+		fmt.Fprintln(messages, "Release groups filtered:", strings.Join(summary, ", "))
+	}
+	return groups
+}
+
+func filterBySecondaryType(groups []mb2.ReleaseGroup, scc setCoverConfig) []mb2.ReleaseGroup {
+	var filtered []mb2.ReleaseGroup
+ReleaseGroupLoop:
+	for _, rg := range groups {
+		for _, dType := range scc.DSec {
+			for _, sType := range rg.SecondaryTypes {
+				if strings.EqualFold(dType, sType) {
+					continue ReleaseGroupLoop
+				}
+			}
+		}
+		filtered = append(filtered, rg)
+	}
+	return filtered
+}
+
+func filterByPrimaryType(groups []mb2.ReleaseGroup, scc setCoverConfig) []mb2.ReleaseGroup {
+	var filtered []mb2.ReleaseGroup
+	for _, rg := range groups {
+		if len(scc.Primary) > 0 && !containsFold(scc.Primary, rg.PrimaryType) {
+			continue
+		}
+		if containsFold(scc.DPrimary, rg.PrimaryType) {
+			continue
+		}
+		filtered = append(filtered, rg)
+	}
+	return filtered
+}
+
+func containsFold(list []string, s string) bool {
+	return slices.ContainsFunc(list, func(el string) bool {
+		return strings.EqualFold(el, s)
+	})
+}
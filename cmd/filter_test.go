@@ -0,0 +1,172 @@
+package cmd
+
+import (
+	"slices"
+	"testing"
+
+	mb2 "go.uploadedlobster.com/musicbrainzws2"
+)
+
+func TestFilterReleaseGroups(t *testing.T) {
+	groups := []mb2.ReleaseGroup{
+		{Title: "Album", PrimaryType: "Album"},
+		{Title: "Live Album", PrimaryType: "Album", SecondaryTypes: []string{"Live"}},
+		{Title: "EP", PrimaryType: "EP"},
+		{Title: "Single", PrimaryType: "Single"},
+		{Title: "Broadcast", PrimaryType: "Broadcast"},
+		{Title: "Other", PrimaryType: "Other"},
+	}
+	cases := []struct {
+		Flags setCoverFlags
+		Want  []string
+	}{
+		{setCoverFlags{}, []string{"Album", "Live Album", "EP", "Single", "Broadcast", "Other"}},
+		{setCoverFlags{Primary: []string{"album", "ep"}}, []string{"Album", "Live Album", "EP"}},
+		{setCoverFlags{DPrimary: []string{"single", "other"}}, []string{"Album", "Live Album", "EP", "Broadcast"}},
+		{setCoverFlags{Primary: []string{"album", "ep"}, DSec: []string{"live"}}, []string{"Album", "EP"}},
+		{setCoverFlags{Primary: []string{"album", "ep"}, DPrimary: []string{"ep"}}, []string{"Album", "Live Album"}},
+	}
+	for _, c := range cases {
+		var titles []string
+		for _, rg := range filterReleaseGroups(groups, setCoverConfig{setCoverFlags: c.Flags}) {
+			titles = append(titles, rg.Title)
+		}
+		if !slices.Equal(titles, c.Want) {
+			t.Errorf(`filterReleaseGroups with %+v = %v, wanted %v`, c.Flags, titles, c.Want)
+		}
+	}
+}
+
+func TestFilterReleases(t *testing.T) {
+	date := func(s string) mb2.Date {
+		d, _ := parsePartialDate(s, false)
+		return mb2.Date{Time: d}
+	}
+	groups := []mb2.ReleaseGroup{
+		{Title: "Album", Releases: []mb2.Release{
+			{Title: "CD", Status: "Official", Country: "US", Date: date("2001-05-01"), Packaging: "Jewel Case", Media: []mb2.Medium{{Format: "CD"}}},
+			{Title: "Vinyl", Status: "Official", Country: "GB", Date: date("1999"), Media: []mb2.Medium{{Format: `12" Vinyl`}, {Format: `7" Vinyl`}}},
+			{Title: "Vinyl+CD", Status: "Official", Country: "XE", Date: date("2010-12"), Packaging: "Digipak", Media: []mb2.Medium{{Format: `12" Vinyl`}, {Format: "CD"}}},
+			{Title: "Undated", Status: "Official", ReleaseEvents: []mb2.ReleaseEvent{{Area: &mb2.Area{Name: "Japan", ISO31661Codes: []string{"JP"}}}}, Media: []mb2.Medium{{Format: "CD"}}},
+		}},
+		{Title: "Promo", Releases: []mb2.Release{
+			{Title: "SACD", Status: "Promotion", Country: "JP", Date: date("2003"), Media: []mb2.Medium{{Format: "SACD"}}},
+		}},
+		{Title: "Bootleg", Releases: []mb2.Release{
+			{Title: "Tape", Status: "Bootleg", Country: "US", Date: date("2005"), Media: []mb2.Medium{{Format: "Cassette"}}},
+		}},
+	}
+	cases := []struct {
+		Flags setCoverFlags
+		Want  []string
+	}{
+		{setCoverFlags{}, []string{"CD", "Vinyl", "Vinyl+CD", "Undated", "SACD", "Tape"}},
+		{setCoverFlags{Status: []string{"official", "promotion"}}, []string{"CD", "Vinyl", "Vinyl+CD", "Undated", "SACD"}},
+		{setCoverFlags{DFormat: []string{`12" vinyl`, `7" vinyl`, "cassette"}}, []string{"CD", "Vinyl+CD", "Undated", "SACD"}},
+		{setCoverFlags{DFormat: []string{"cd"}}, []string{"Vinyl", "Vinyl+CD", "SACD", "Tape"}},
+		{setCoverFlags{Country: []string{"us", "japan"}}, []string{"CD", "Undated", "Tape"}},
+		{setCoverFlags{Country: []string{"jp"}}, []string{"Undated", "SACD"}},
+		{setCoverFlags{Since: "2001"}, []string{"CD", "Vinyl+CD", "SACD", "Tape"}},
+		{setCoverFlags{Until: "2010-11"}, []string{"CD", "Vinyl", "SACD", "Tape"}},
+		{setCoverFlags{Since: "2000", Until: "2010"}, []string{"CD", "Vinyl+CD", "SACD", "Tape"}},
+		{setCoverFlags{DPackaging: []string{"digipak"}}, []string{"CD", "Vinyl", "Undated", "SACD", "Tape"}},
+	}
+	for _, c := range cases {
+		var titles []string
+		for _, rg := range filterReleases(groups, setCoverConfig{setCoverFlags: c.Flags}) {
+			for _, r := range rg.Releases {
+				titles = append(titles, r.Title)
+			}
+		}
+		if !slices.Equal(titles, c.Want) {
+			t.Errorf(`filterReleases with %+v = %v, wanted %v`, c.Flags, titles, c.Want)
+		}
+	}
+	if len(groups[0].Releases) != 4 {
+		t.Errorf(`filterReleases modified its input %v`, groups)
+	}
+}
+
+func TestValidateReleaseFilters(t *testing.T) {
+	cases := []struct {
+		Flags setCoverFlags
+		Error bool
+	}{
+		{setCoverFlags{Status: []string{"Official", "pseudo-release"}}, false},
+		{setCoverFlags{Status: []string{"offical"}}, true},
+		{setCoverFlags{Since: "2000-13"}, true},
+	}
+	for _, c := range cases {
+		if err := validateReleaseFilters(setCoverConfig{setCoverFlags: c.Flags}); (err != nil) != c.Error {
+			t.Errorf(`validateReleaseFilters with %+v returned error %v`, c.Flags, err)
+		}
+	}
+}
+
+func TestParsePartialDate(t *testing.T) {
+	cases := []struct {
+		In    string
+		End   bool
+		Want  string
+		Error bool
+	}{
+		{"2000", false, "2000-01-01", false},
+		{"2000", true, "2001-01-01", false},
+		{"2000-02", true, "2000-03-01", false},
+		{"2000-02-28", true, "2000-02-29", false},
+		{"02/28/2000", false, "", true},
+	}
+	for _, c := range cases {
+		res, err := parsePartialDate(c.In, c.End)
+		if (err != nil) != c.Error {
+			t.Errorf(`parsePartialDate(%q, %v) returned error %v`, c.In, c.End, err)
+			continue
+		}
+		if !c.Error && res.Format("2006-01-02") != c.Want {
+			t.Errorf(`parsePartialDate(%q, %v) = %v, wanted %v`, c.In, c.End, res, c.Want)
+		}
+	}
+}
+
+func TestFilterByExpression(t *testing.T) {
+	groups := []mb2.ReleaseGroup{
+		{Title: "Album", Releases: []mb2.Release{
+			{Title: "US", Country: "US", Media: []mb2.Medium{{Format: "CD", Tracks: []mb2.Track{
+				{Title: "a"},
+				{Title: "b (2011 Remaster)"},
+			}}}},
+			{Title: "JP", Country: "JP", Media: []mb2.Medium{{Format: "CD", Tracks: []mb2.Track{
+				{Title: "a"},
+			}}}},
+		}},
+		{Title: "Remasters", Releases: []mb2.Release{
+			{Title: "GB", Country: "GB", Media: []mb2.Medium{{Format: "CD", Tracks: []mb2.Track{
+				{Title: "c (Remastered)"},
+			}}}},
+		}},
+	}
+	scc := setCoverConfig{setCoverFlags: setCoverFlags{
+		Filter: `release.country in ["US","GB"] and not track.title ~ "(?i)remaster"`,
+	}}
+	res, err := filterByExpression(groups, scc)
+	if err != nil {
+		t.Fatalf(`filterByExpression returned error: %v`, err)
+	}
+	if len(res) != 1 || len(res[0].Releases) != 1 || !slices.Equal(releaseTrackTitles(res[0].Releases[0], scc), []string{"a"}) {
+		t.Errorf(`filterByExpression(%v) = %v, wanted only track a of the US release`, groups, res)
+	}
+	if len(groups[0].Releases[0].Media[0].Tracks) != 2 {
+		t.Errorf(`filterByExpression modified its input %v`, groups)
+	}
+
+	for _, src := range []string{`release.colour == "red"`, `release.country ==`} {
+		scc.Filter = src
+		if _, err := filterByExpression(groups, scc); err == nil {
+			t.Errorf(`filterByExpression with %q did not return an error`, src)
+		}
+	}
+	scc.Filter = `release.country < 2000`
+	if _, err := filterByExpression(groups, scc); err == nil {
+		t.Errorf(`filterByExpression with %q did not return an error`, scc.Filter)
+	}
+}
@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/frigorific44/musicgreed/beets"
+	"github.com/frigorific44/musicgreed/jellyfin"
+	mb2 "go.uploadedlobster.com/musicbrainzws2"
+)
+
+const (
+	libraryBeets    string = "beets"
+	libraryJellyfin string = "jellyfin"
+	jellyfinKeyEnv  string = "MUSICGREED_JELLYFIN_KEY"
+)
+
+// A music library holding the tracks already collected.
+type musicLibrary interface {
+	ArtistTrackTitles(id mb2.MBID) ([]mb2.Track, error)
+}
+
+type beetsLibrary struct{}
+
+func (beetsLibrary) ArtistTrackTitles(id mb2.MBID) ([]mb2.Track, error) {
+	return beets.ArtistTrackTitles(id)
+}
+
+// Opens the configured music library, to be shared by every artist of a run.
+// Without remainder mode, no library is needed.
+func openLibrary(flags setCoverFlags) (musicLibrary, error) {
+	if !flags.Remainder {
+		return nil, nil
+	}
+	switch flags.Library {
+	case libraryBeets, "":
+		return beetsLibrary{}, nil
+	case libraryJellyfin:
+		if flags.JellyfinURL == "" {
+			return nil, fmt.Errorf(`the jellyfin library requires --jellyfin-url`)
+		}
+		key := flags.JellyfinKey
+		if key == "" {
+			key = os.Getenv(jellyfinKeyEnv)
+		}
+		return jellyfin.NewClient(flags.JellyfinURL, key), nil
+	}
+	return nil, fmt.Errorf(`unknown music library %q`, flags.Library)
+}
+
+// Retrieves the artist's tracks from the opened music library.
+func libraryTracks(scc setCoverConfig, id mb2.MBID) ([]mb2.Track, error) {
+	if scc.MusicLibrary == nil {
+		return nil, fmt.Errorf(`no music library opened`)
+	}
+	return scc.MusicLibrary.ArtistTrackTitles(id)
+}
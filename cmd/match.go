@@ -0,0 +1,153 @@
+package cmd
+
+import (
+	"cmp"
+	"fmt"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/frigorific44/musicgreed/acoustid"
+	"github.com/frigorific44/musicgreed/titlematch"
+	mb2 "go.uploadedlobster.com/musicbrainzws2"
+)
+
+const (
+	acoustIDKeyEnv        string = "MUSICGREED_ACOUSTID_KEY"
+	canonicalLongest      string = "longest"
+	canonicalMostFrequent string = "most-frequent"
+	canonicalShortest     string = "shortest"
+	canonicalEarliest     string = "earliest"
+)
+
+var (
+	canonicalRules []string = []string{canonicalLongest, canonicalMostFrequent, canonicalShortest, canonicalEarliest}
+)
+
+// Titles taken as the same song, each substituted by the canonical title.
+type titleGroup struct {
+	Canonical string   `json:"canonical"`
+	Titles    []string `json:"titles"`
+}
+
+// What's known of a title across the releases carrying it.
+type titleStats struct {
+	Tracks int
+	First  time.Time
+}
+
+func (ts titleStats) add(release mb2.Release) titleStats {
+	ts.Tracks++
+	if date := release.Date.Time; !date.IsZero() && (ts.First.IsZero() || date.Before(ts.First)) {
+		ts.First = date
+	}
+	return ts
+}
+
+// Orders equivalent titles with the canonical title by the rule first, the
+// rule's ties going to the longest title and then alphabetically.
+func canonicalOrder(rule string, stats map[string]titleStats) func(a, b string) int {
+	return func(a, b string) int {
+		var c int
+		switch rule {
+		case canonicalMostFrequent:
+			c = -1 * cmp.Compare(stats[a].Tracks, stats[b].Tracks)
+		case canonicalShortest:
+			c = cmp.Compare(len(a), len(b))
+		case canonicalEarliest:
+			// Titles without a dated release come last.
+			firstA, firstB := stats[a].First, stats[b].First
+			switch {
+			case firstA.IsZero() && !firstB.IsZero():
+				c = 1
+			case !firstA.IsZero() && firstB.IsZero():
+				c = -1
+			default:
+				c = firstA.Compare(firstB)
+			}
+		}
+		if c != 0 {
+			return c
+		}
+		if c = -1 * cmp.Compare(len(a), len(b)); c != 0 {
+			return c
+		}
+		return cmp.Compare(a, b)
+	}
+}
+
+// Returns the name of the metric for comparing cleaned titles and the
+// similarity above which titles are alike.
+func titleMetric(flags setCoverFlags) (string, float64, error) {
+	name := flags.Metric
+	if name == "" {
+		name = titlematch.Levenshtein
+	}
+	if _, err := titlematch.NewMetric(name); err != nil {
+		return name, 0, err
+	}
+	threshold := flags.Threshold
+	if threshold == 0 {
+		threshold = titlematch.DefaultThresholds[name]
+	}
+	if threshold < 0 || threshold >= 1 {
+		return name, 0, fmt.Errorf(`similarity threshold %v out of range, expected between 0 and 1`, threshold)
+	}
+	return name, threshold, nil
+}
+
+// Returns the configured AcoustID lookup, preferring a local dump over a
+// server, or nil when neither is configured.
+func acoustIDLookup(flags setCoverFlags) acoustid.Lookup {
+	if flags.AcoustIDDump != "" {
+		return acoustid.NewDumpLookup(flags.AcoustIDDump)
+	}
+	key := flags.AcoustIDKey
+	if key == "" {
+		key = os.Getenv(acoustIDKeyEnv)
+	}
+	if flags.AcoustIDServer != "" || key != "" {
+		return acoustid.NewServerLookup(flags.AcoustIDServer, key)
+	}
+	return nil
+}
+
+// Groups the titles whose recordings share an AcoustID, each group sorted and
+// holding more than one title.
+func acoustIDGroups(lookup acoustid.Lookup, titleSet map[string]bool, titleRecordings map[string][]mb2.MBID) ([][]string, error) {
+	var recordings []mb2.MBID
+	for t := range titleSet {
+		recordings = append(recordings, titleRecordings[t]...)
+	}
+	slices.Sort(recordings)
+	recordings = slices.Compact(recordings)
+	if len(recordings) == 0 {
+		return nil, nil
+	}
+	ids, err := lookup.AcoustIDs(recordings)
+	if err != nil {
+		return nil, err
+	}
+
+	titlesByID := make(map[string][]string)
+	for t := range titleSet {
+		for _, rec := range titleRecordings[t] {
+			for _, id := range ids[rec] {
+				if !slices.Contains(titlesByID[id], t) {
+					titlesByID[id] = append(titlesByID[id], t)
+				}
+			}
+		}
+	}
+	var groups [][]string
+	for _, titles := range titlesByID {
+		if len(titles) > 1 {
+			slices.Sort(titles)
+			groups = append(groups, titles)
+		}
+	}
+	slices.SortFunc(groups, func(a, b []string) int {
+		return slices.Compare(a, b)
+	})
+	return groups, nil
+}
@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+
+	mb2 "go.uploadedlobster.com/musicbrainzws2"
+)
+
+type fakeLookup map[mb2.MBID][]string
+
+func (f fakeLookup) AcoustIDs(recordings []mb2.MBID) (map[mb2.MBID][]string, error) {
+	ids := make(map[mb2.MBID][]string)
+	for _, rec := range recordings {
+		if id, ok := f[rec]; ok {
+			ids[rec] = id
+		}
+	}
+	return ids, nil
+}
+
+func TestAcoustIDGroups(t *testing.T) {
+	lookup := fakeLookup{
+		"rec-a":  {"aaaa"},
+		"rec-a2": {"aaaa"},
+		"rec-b":  {"bbbb"},
+		"rec-c":  {"bbbb", "cccc"},
+		"rec-d":  {"dddd"},
+		"rec-e":  {"cccc"},
+	}
+	titleSet := map[string]bool{"A": true, "A (2009)": true, "B": true, "C": true, "D": true}
+	titleRecordings := map[string][]mb2.MBID{
+		"A":        {"rec-a"},
+		"A (2009)": {"rec-a2"},
+		"B":        {"rec-b"},
+		"C":        {"rec-c"},
+		"D":        {"rec-d"},
+		"E":        {"rec-e"},
+	}
+	groups, err := acoustIDGroups(lookup, titleSet, titleRecordings)
+	if err != nil {
+		t.Fatalf(`acoustIDGroups returned error: %v`, err)
+	}
+	want := [][]string{{"A", "A (2009)"}, {"B", "C"}}
+	if !reflect.DeepEqual(groups, want) {
+		t.Errorf(`acoustIDGroups = %v, wanted %v`, groups, want)
+	}
+}
+
+func TestCanonicalOrder(t *testing.T) {
+	date := func(year int) time.Time {
+		return time.Date(year, 1, 1, 0, 0, 0, 0, time.UTC)
+	}
+	titles := []string{"Song", "Song!", "The Song", "Song (Album Version)"}
+	stats := map[string]titleStats{
+		"Song":                 {Tracks: 2, First: date(2004)},
+		"Song!":                {Tracks: 5},
+		"The Song":             {Tracks: 5, First: date(2001)},
+		"Song (Album Version)": {Tracks: 1, First: date(2001)},
+	}
+	cases := []struct {
+		Rule string
+		Want string
+	}{
+		{"", "Song (Album Version)"},
+		{canonicalLongest, "Song (Album Version)"},
+		{canonicalMostFrequent, "The Song"},
+		{canonicalShortest, "Song"},
+		{canonicalEarliest, "Song (Album Version)"},
+	}
+	for _, c := range cases {
+		set := slices.Clone(titles)
+		slices.SortFunc(set, canonicalOrder(c.Rule, stats))
+		if set[0] != c.Want {
+			t.Errorf(`canonicalOrder(%q) put "%v" first, wanted "%v"`, c.Rule, set[0], c.Want)
+		}
+	}
+}
+
+func TestTitleMetric(t *testing.T) {
+	cases := []struct {
+		Flags     setCoverFlags
+		Threshold float64
+		Error     bool
+	}{
+		{setCoverFlags{}, 0.6, false},
+		{setCoverFlags{Metric: "jaro-winkler"}, 0.85, false},
+		{setCoverFlags{Metric: "token-set", Threshold: 0.7}, 0.7, false},
+		{setCoverFlags{Metric: "soundex"}, 0, true},
+		{setCoverFlags{Threshold: 1.5}, 0, true},
+	}
+	for _, c := range cases {
+		_, threshold, err := titleMetric(c.Flags)
+		if (err != nil) != c.Error {
+			t.Errorf(`titleMetric(%+v) returned error %v`, c.Flags, err)
+			continue
+		}
+		if threshold != c.Threshold {
+			t.Errorf(`titleMetric(%+v) threshold = %v, wanted %v`, c.Flags, threshold, c.Threshold)
+		}
+	}
+}
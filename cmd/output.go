@@ -0,0 +1,113 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+
+	"github.com/spf13/cobra"
+	mb2 "go.uploadedlobster.com/musicbrainzws2"
+)
+
+const (
+	formatText string = "text"
+	formatJSON string = "json"
+)
+
+const (
+	// The annotation of commands whose messages always go to standard error.
+	annotationStderr string = "musicgreed-stderr"
+)
+
+var (
+	// Progress and status messages, kept apart from the result in JSON output.
+	// Resolved once before any command runs.
+	messages io.Writer = os.Stdout
+)
+
+func addOutputFlags(cmd *cobra.Command) {
+	cmd.Flags().String("format", formatText, "output format (text, json)")
+}
+
+// Returns the validated output format.
+func outputFormat(cmd *cobra.Command) (string, error) {
+	format, _ := cmd.Flags().GetString("format")
+	if format != formatText && format != formatJSON {
+		return format, fmt.Errorf(`unknown output format %q, expected %v or %v`, format, formatText, formatJSON)
+	}
+	return format, nil
+}
+
+// Returns where the command's messages go: standard error when the result on
+// standard output isn't text, or when the command asks for it.
+func messageWriter(cmd *cobra.Command) io.Writer {
+	if _, ok := cmd.Annotations[annotationStderr]; ok {
+		return os.Stderr
+	}
+	if format, err := cmd.Flags().GetString("format"); err == nil && format != formatText {
+		return os.Stderr
+	}
+	return os.Stdout
+}
+
+func writeJSON(v any) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+type setCoverResult struct {
+	Artists      []mb2.MBID            `json:"artists"`
+	Flags        setCoverFlags         `json:"flags"`
+	Songs        []string              `json:"songs"`
+	Releases     []releaseResult       `json:"releases"`
+	Ownership    []groupOwnership      `json:"ownership,omitempty"`
+	Titles       []titleGroup          `json:"titleGroups"`
+	Summary      coverSummary          `json:"summary"`
+	Covers       [][]coverContribution `json:"covers"`
+	Explanations []releaseExplanation  `json:"explanations,omitempty"`
+	Plan         *purchasePlan         `json:"plan,omitempty"`
+}
+
+type releaseResult struct {
+	ID     mb2.MBID `json:"id"`
+	Title  string   `json:"title"`
+	Group  string   `json:"group"`
+	Tracks []string `json:"tracks"`
+}
+
+// Gathers the set cover calculation into a result for JSON output.
+func newSetCoverResult(releases []mb2.Release, covers [][]mb2.Release, scc setCoverConfig) setCoverResult {
+	result := setCoverResult{
+		Artists: scc.ArtistMBIDs,
+		Flags:   scc.setCoverFlags,
+		Songs:   []string{},
+		Titles:  scc.TitleGroups,
+		Covers:  [][]coverContribution{},
+	}
+	if result.Titles == nil {
+		result.Titles = []titleGroup{}
+	}
+	songs := make(map[string]bool)
+	for _, r := range releases {
+		rr := releaseResult{ID: r.ID, Title: r.Title, Tracks: releaseTrackTitles(r, scc)}
+		if r.ReleaseGroup != nil {
+			rr.Group = r.ReleaseGroup.Title
+		}
+		for _, t := range rr.Tracks {
+			if !songs[t] {
+				songs[t] = true
+				result.Songs = append(result.Songs, t)
+			}
+		}
+		result.Releases = append(result.Releases, rr)
+	}
+	slices.Sort(result.Songs)
+	result.Summary = summarizeCovers(covers)
+	for _, msc := range covers {
+		result.Covers = append(result.Covers, sortedContributions(msc, scc))
+	}
+	return result
+}
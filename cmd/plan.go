@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+
+	mb2 "go.uploadedlobster.com/musicbrainzws2"
+)
+
+// An order to buy the releases of a set cover in, each adding the most songs
+// not yet covered.
+type purchasePlan struct {
+	Songs int        `json:"songs"`
+	Owned int        `json:"owned"`
+	Steps []planStep `json:"steps"`
+}
+
+type planStep struct {
+	Title string   `json:"title"`
+	ID    mb2.MBID `json:"id"`
+	// The songs the release adds to those covered before it.
+	Tracks []string `json:"tracks"`
+	// The percentage of songs covered once bought, counting those owned.
+	Coverage float64 `json:"coverage"`
+}
+
+// Plans the purchase of the cover greedily by marginal gain. Songs already in
+// the library, on any release of the groups, count toward coverage from the
+// start, including those on releases owned in full and left out of the cover.
+func newPurchasePlan(groups []mb2.ReleaseGroup, cover []mb2.Release, scc setCoverConfig) purchasePlan {
+	owned := make(map[string]bool)
+	songs := make(map[string]bool)
+	for _, rg := range groups {
+		for _, r := range rg.Releases {
+			for _, t := range releaseOwnedTitles(r, scc) {
+				owned[t] = true
+			}
+			for _, t := range releaseTrackTitles(r, scc) {
+				songs[t] = true
+			}
+		}
+	}
+	plan := purchasePlan{Songs: len(songs) + len(owned), Owned: len(owned), Steps: []planStep{}}
+
+	covered := len(owned)
+	remaining := slices.Clone(cover)
+	for len(remaining) > 0 {
+		best, bestTracks := -1, []string{}
+		for i, r := range remaining {
+			var tracks []string
+			for _, t := range releaseTrackTitles(r, scc) {
+				if songs[t] {
+					tracks = append(tracks, t)
+				}
+			}
+			tracks = slices.Compact(tracks)
+			if best < 0 || len(tracks) > len(bestTracks) ||
+				len(tracks) == len(bestTracks) && cmp.Compare(r.Title, remaining[best].Title) < 0 {
+				best, bestTracks = i, tracks
+			}
+		}
+		if len(bestTracks) == 0 {
+			break
+		}
+		for _, t := range bestTracks {
+			delete(songs, t)
+		}
+		covered += len(bestTracks)
+		plan.Steps = append(plan.Steps, planStep{
+			Title:    remaining[best].Title,
+			ID:       remaining[best].ID,
+			Tracks:   bestTracks,
+			Coverage: 100 * float64(covered) / float64(plan.Songs),
+		})
+		remaining = slices.Delete(remaining, best, best+1)
+	}
+	return plan
+}
+
+func printPurchasePlan(plan purchasePlan) {
+	fmt.Println("\nPurchase Plan")
+	fmt.Println(horizontal)
+	fmt.Println("Step | New Songs | Coverage | Release")
+	fmt.Println(horizontal)
+	if plan.Owned > 0 {
+		owned := 100 * float64(plan.Owned) / float64(plan.Songs)
+		fmt.Printf("%-6v %-11v %-10v %v\n", "-", plan.Owned, fmt.Sprintf("%.1f%%", owned), "(already owned)")
+	}
+	for i, s := range plan.Steps {
+		fmt.Printf("%-6v %-11v %-10v %v\n", i+1, len(s.Tracks), fmt.Sprintf("%.1f%%", s.Coverage), s.Title)
+	}
+	fmt.Println("\nNew Songs:")
+	for i, s := range plan.Steps {
+		fmt.Printf("%v. %v: %v\n", i+1, s.Title, strings.Join(s.Tracks, "; "))
+	}
+}
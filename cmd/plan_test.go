@@ -0,0 +1,43 @@
+package cmd
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+
+	mb2 "go.uploadedlobster.com/musicbrainzws2"
+)
+
+func TestPurchasePlan(t *testing.T) {
+	cover := []mb2.Release{
+		testRelease("Third", "e"),
+		testRelease("Second", "c", "d"),
+		testRelease("First", "a", "b", "c", "z"),
+	}
+	// Owned in full, so it's absent from the cover but its songs still count.
+	owned := testRelease("Owned", "y", "z")
+	groups := []mb2.ReleaseGroup{{Releases: append(slices.Clone(cover), owned)}}
+	scc := setCoverConfig{TitleOwned: map[string]bool{"y": true, "z": true}}
+	plan := newPurchasePlan(groups, cover, scc)
+	if plan.Songs != 7 || plan.Owned != 2 {
+		t.Errorf(`newPurchasePlan(%v) counted %v songs and %v owned, wanted 7 and 2`, cover, plan.Songs, plan.Owned)
+	}
+	want := []struct {
+		Title    string
+		Tracks   []string
+		Coverage string
+	}{
+		{"First", []string{"a", "b", "c"}, "71.4"},
+		{"Second", []string{"d"}, "85.7"},
+		{"Third", []string{"e"}, "100.0"},
+	}
+	if len(plan.Steps) != len(want) {
+		t.Fatalf(`newPurchasePlan(%v) = %+v, wanted %v steps`, cover, plan.Steps, len(want))
+	}
+	for i, w := range want {
+		s := plan.Steps[i]
+		if s.Title != w.Title || !slices.Equal(s.Tracks, w.Tracks) || fmt.Sprintf("%.1f", s.Coverage) != w.Coverage {
+			t.Errorf(`step %v of newPurchasePlan(%v) = %+v, wanted %+v`, i+1, cover, s, w)
+		}
+	}
+}
@@ -0,0 +1,180 @@
+package cmd
+
+import (
+	"cmp"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	mb2 "go.uploadedlobster.com/musicbrainzws2"
+)
+
+const (
+	rankTracks  string = "tracks"
+	rankRecent  string = "recent"
+	rankCountry string = "country:"
+	rankFormat  string = "format:"
+	rankDigital string = "digital"
+	rankCost    string = "cost"
+)
+
+var (
+	rankCriteria []string = []string{rankTracks, rankRecent, rankCountry + "XX", rankFormat + "name", rankDigital, rankCost}
+)
+
+// Scores a cover by a ranking criterion, with lower scores ranking first.
+type coverScore func(cover []mb2.Release, prices map[mb2.MBID]float64) float64
+
+// Returns the score of the criterion, either a name or a prefix followed by a value.
+func rankCriterion(criterion string) (coverScore, error) {
+	switch {
+	case criterion == rankTracks:
+		// Fewest tracks in all, wasting the least on songs already covered.
+		return func(cover []mb2.Release, _ map[mb2.MBID]float64) float64 {
+			var tracks int
+			for _, r := range cover {
+				for _, m := range r.Media {
+					tracks += len(m.Tracks)
+				}
+			}
+			return float64(tracks)
+		}, nil
+	case criterion == rankRecent:
+		// The latest average release date. Undated releases have the zero time,
+		// counting as the oldest.
+		return func(cover []mb2.Release, _ map[mb2.MBID]float64) float64 {
+			var sum float64
+			for _, r := range cover {
+				sum += float64(r.Date.Time.Unix())
+			}
+			return -sum / float64(len(cover))
+		}, nil
+	case strings.HasPrefix(criterion, rankCountry) && len(criterion) > len(rankCountry):
+		country := strings.TrimPrefix(criterion, rankCountry)
+		return countReleases(func(r mb2.Release) bool { return strings.EqualFold(r.Country, country) }), nil
+	case strings.HasPrefix(criterion, rankFormat) && len(criterion) > len(rankFormat):
+		format := strings.TrimPrefix(criterion, rankFormat)
+		return countReleases(func(r mb2.Release) bool { return hasFormat(r, format) }), nil
+	case criterion == rankDigital:
+		return countReleases(func(r mb2.Release) bool { return hasFormat(r, "digital") }), nil
+	case criterion == rankCost:
+		// The lowest total price, with covers of unpriced releases ranking last.
+		return func(cover []mb2.Release, prices map[mb2.MBID]float64) float64 {
+			var cost float64
+			for _, r := range cover {
+				price, ok := prices[r.ID]
+				if !ok {
+					return math.Inf(1)
+				}
+				cost += price
+			}
+			return cost
+		}, nil
+	}
+	return nil, fmt.Errorf(`unknown ranking criterion %q, expected one of %v`, criterion, rankCriteria)
+}
+
+// Scores covers with more releases satisfying the predicate first.
+func countReleases(predicate func(mb2.Release) bool) coverScore {
+	return func(cover []mb2.Release, _ map[mb2.MBID]float64) float64 {
+		var count int
+		for _, r := range cover {
+			if predicate(r) {
+				count += 1
+			}
+		}
+		return -float64(count)
+	}
+}
+
+func hasFormat(release mb2.Release, format string) bool {
+	for _, m := range release.Media {
+		if strings.Contains(strings.ToLower(m.Format), strings.ToLower(format)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Orders the covers by the criteria, each breaking the ties of those before
+// it, and keeps the top covers when top is positive.
+func rankCovers(covers [][]mb2.Release, criteria []string, prices map[mb2.MBID]float64, top int) ([][]mb2.Release, error) {
+	var scores []coverScore
+	for _, c := range criteria {
+		score, err := rankCriterion(c)
+		if err != nil {
+			return covers, err
+		}
+		scores = append(scores, score)
+	}
+	type scored struct {
+		Cover  []mb2.Release
+		Scores []float64
+	}
+	ranked := make([]scored, len(covers))
+	for i, cover := range covers {
+		ranked[i].Cover = cover
+		for _, score := range scores {
+			ranked[i].Scores = append(ranked[i].Scores, score(cover, prices))
+		}
+	}
+	slices.SortStableFunc(ranked, func(a, b scored) int {
+		for i := range a.Scores {
+			if c := cmp.Compare(a.Scores[i], b.Scores[i]); c != 0 {
+				return c
+			}
+		}
+		return 0
+	})
+	result := make([][]mb2.Release, len(ranked))
+	for i, r := range ranked {
+		result[i] = r.Cover
+	}
+	if top > 0 && top < len(result) {
+		result = result[:top]
+	}
+	return result, nil
+}
+
+// Reads release prices from a CSV file with mbid and price columns.
+func readPrices(path string) (map[mb2.MBID]float64, error) {
+	prices := make(map[mb2.MBID]float64)
+	if path == "" {
+		return prices, nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return prices, fmt.Errorf(`opening prices: %w`, err)
+	}
+	defer f.Close()
+	reader := csv.NewReader(f)
+	header, err := reader.Read()
+	if err != nil {
+		return prices, fmt.Errorf(`reading prices header: %w`, err)
+	}
+	mbidCol := slices.Index(header, "mbid")
+	priceCol := slices.Index(header, "price")
+	if mbidCol < 0 || priceCol < 0 {
+		return prices, fmt.Errorf(`prices header %v lacks mbid and price columns`, header)
+	}
+	for {
+		row, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return prices, fmt.Errorf(`reading prices: %w`, err)
+		}
+		price, err := strconv.ParseFloat(strings.TrimSpace(row[priceCol]), 64)
+		if err != nil {
+			return prices, fmt.Errorf(`price of release %v: %w`, row[mbidCol], err)
+		}
+		prices[mb2.MBID(row[mbidCol])] = price
+	}
+	return prices, nil
+}
@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	mb2 "go.uploadedlobster.com/musicbrainzws2"
+)
+
+func TestRankCovers(t *testing.T) {
+	common := mb2.Release{ID: "common", Country: "US", Media: []mb2.Medium{{Format: "CD", Tracks: make([]mb2.Track, 10)}}}
+	covers := [][]mb2.Release{
+		{common, {ID: "us-cd", Country: "US", Media: []mb2.Medium{{Format: "CD", Tracks: make([]mb2.Track, 12)}}}},
+		{common, {ID: "gb-vinyl", Country: "GB", Media: []mb2.Medium{{Format: `12" Vinyl`, Tracks: make([]mb2.Track, 8)}}}},
+		{common, {ID: "gb-digital", Country: "GB", Media: []mb2.Medium{{Format: "Digital Media", Tracks: make([]mb2.Track, 12)}}}},
+	}
+	prices := map[mb2.MBID]float64{"common": 10, "us-cd": 5, "gb-digital": 4}
+	cases := []struct {
+		Criteria []string
+		Top      int
+		Want     []mb2.MBID
+	}{
+		{nil, 0, []mb2.MBID{"us-cd", "gb-vinyl", "gb-digital"}},
+		{[]string{"tracks"}, 0, []mb2.MBID{"gb-vinyl", "us-cd", "gb-digital"}},
+		{[]string{"country:gb", "tracks"}, 0, []mb2.MBID{"gb-vinyl", "gb-digital", "us-cd"}},
+		{[]string{"digital"}, 1, []mb2.MBID{"gb-digital"}},
+		{[]string{"format:vinyl"}, 2, []mb2.MBID{"gb-vinyl", "us-cd"}},
+		{[]string{"cost"}, 0, []mb2.MBID{"gb-digital", "us-cd", "gb-vinyl"}},
+	}
+	for _, c := range cases {
+		res, err := rankCovers(covers, c.Criteria, prices, c.Top)
+		if err != nil {
+			t.Errorf(`rankCovers(%v) returned %v`, c.Criteria, err)
+			continue
+		}
+		var ids []mb2.MBID
+		for _, cover := range res {
+			ids = append(ids, cover[1].ID)
+		}
+		if !slices.Equal(ids, c.Want) {
+			t.Errorf(`rankCovers(%v, top %v) = %v, wanted %v`, c.Criteria, c.Top, ids, c.Want)
+		}
+	}
+	if _, err := rankCovers(covers, []string{"country:"}, prices, 0); err == nil {
+		t.Error(`rankCovers with an empty country did not return an error`)
+	}
+}
+
+func TestReadPrices(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "prices.csv")
+	data := "title,mbid,price\nA,mbid-a,9.99\nB,mbid-b, 4\n"
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	prices, err := readPrices(path)
+	if err != nil {
+		t.Fatalf(`readPrices returned %v`, err)
+	}
+	if len(prices) != 2 || prices["mbid-a"] != 9.99 || prices["mbid-b"] != 4 {
+		t.Errorf(`readPrices = %v, wanted mbid-a at 9.99 and mbid-b at 4`, prices)
+	}
+
+	if err := os.WriteFile(path, []byte("mbid,cost\nmbid-a,1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := readPrices(path); err == nil {
+		t.Error(`readPrices without a price column did not return an error`)
+	}
+}
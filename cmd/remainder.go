@@ -0,0 +1,162 @@
+package cmd
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+
+	"github.com/frigorific44/musicgreed/musicinfo"
+	"github.com/spf13/cobra"
+	mb2 "go.uploadedlobster.com/musicbrainzws2"
+)
+
+// remainderCmd represents the remainder command
+func NewRemainderCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   `remainder artist...`,
+		Short: "List the songs of one or more artists missing from a music library.",
+		Long: "This command lists every unique song released by an artist that is missing " +
+			"from your music library. Songs are grouped under the release group carrying " +
+			"them on the most releases, and sorted by how many releases carry each song, " +
+			"so the most widely available songs come first. The same flags as `setcover` " +
+			"may help to filter out music tracks that aren't of concern." +
+			"\n\n`musicgreed remainder --dalt artist1 artist2`",
+		Args: cobra.MinimumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			flags, err := packageSetCoverFlags(cmd)
+			if err != nil {
+				fmt.Println(err)
+				return
+			}
+			format, err := outputFormat(cmd)
+			if err != nil {
+				fmt.Println(err)
+				return
+			}
+			flags.Remainder = true
+			library, err := openLibrary(flags)
+			if err != nil {
+				fmt.Println(err)
+				return
+			}
+			client, stop := musicinfo.NewMGClient()
+			defer stop()
+
+			var results []artistMissing
+			for _, arg := range args {
+				scc := setCoverConfig{setCoverFlags: flags, MusicLibrary: library}
+				mbid, idErr := artistMBID(client, arg)
+				if idErr != nil {
+					fmt.Fprintf(messages, "Artist ID could not be retrieved for %q\n", arg)
+					continue
+				}
+				scc.ArtistMBIDs = []mb2.MBID{mbid}
+
+				fmt.Fprintf(messages, "Retrieving music for %v...\n", arg)
+				groups, err := artistReleaseGroups(client, &scc)
+				if err != nil {
+					fmt.Fprintln(messages, err)
+					continue
+				}
+				am := artistMissing{Artist: arg, ID: mbid, Groups: missingSongs(groups, scc)}
+				if format == formatJSON {
+					results = append(results, am)
+				} else {
+					printMissingSongs(am)
+				}
+			}
+			if format == formatJSON {
+				if err := writeJSON(results); err != nil {
+					fmt.Fprintln(messages, err)
+				}
+			}
+		},
+	}
+
+	addFilterFlags(cmd)
+	addMatchFlags(cmd)
+	addLibraryFlags(cmd)
+	addOutputFlags(cmd)
+
+	return cmd
+}
+
+type missingSong struct {
+	Title    string
+	Releases int
+}
+
+type groupMissing struct {
+	Title string
+	ID    mb2.MBID
+	Songs []missingSong
+}
+
+type artistMissing struct {
+	Artist string
+	ID     mb2.MBID
+	Groups []groupMissing
+}
+
+// Gathers the songs missing from the library, each listed under the release
+// group carrying it on the most releases.
+func missingSongs(groups []mb2.ReleaseGroup, scc setCoverConfig) []groupMissing {
+	releaseCount := make(map[string]int)
+	groupCount := make(map[string]map[int]int)
+	for gi, rg := range groups {
+		for _, r := range rg.Releases {
+			for _, title := range slices.Compact(releaseTrackTitles(r, scc)) {
+				releaseCount[title] += 1
+				if groupCount[title] == nil {
+					groupCount[title] = make(map[int]int)
+				}
+				groupCount[title][gi] += 1
+			}
+		}
+	}
+
+	songsByGroup := make(map[int][]missingSong)
+	for title, counts := range groupCount {
+		best := -1
+		for gi, c := range counts {
+			if best < 0 || c > counts[best] || (c == counts[best] && gi < best) {
+				best = gi
+			}
+		}
+		songsByGroup[best] = append(songsByGroup[best], missingSong{Title: title, Releases: releaseCount[title]})
+	}
+
+	var missing []groupMissing
+	for gi, songs := range songsByGroup {
+		slices.SortFunc(songs, func(a, b missingSong) int {
+			if c := cmp.Compare(b.Releases, a.Releases); c != 0 {
+				return c
+			}
+			return cmp.Compare(a.Title, b.Title)
+		})
+		missing = append(missing, groupMissing{Title: groups[gi].Title, ID: groups[gi].ID, Songs: songs})
+	}
+	slices.SortFunc(missing, func(a, b groupMissing) int {
+		if c := cmp.Compare(len(b.Songs), len(a.Songs)); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Title, b.Title)
+	})
+	return missing
+}
+
+func printMissingSongs(am artistMissing) {
+	var total int
+	for _, gm := range am.Groups {
+		total += len(gm.Songs)
+	}
+	fmt.Printf("\n> %v, %v missing songs\n", am.Artist, total)
+	for _, gm := range am.Groups {
+		fmt.Println(horizontal)
+		fmt.Println(gm.Title)
+		fmt.Println(horizontal)
+		for _, song := range gm.Songs {
+			fmt.Printf("%-14v %v\n", song.Releases, song.Title)
+		}
+	}
+}
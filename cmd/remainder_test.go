@@ -0,0 +1,33 @@
+package cmd
+
+import (
+	"slices"
+	"testing"
+
+	mb2 "go.uploadedlobster.com/musicbrainzws2"
+)
+
+func TestMissingSongs(t *testing.T) {
+	groups := []mb2.ReleaseGroup{
+		{Title: "Single", Releases: []mb2.Release{testRelease("single", "a", "b")}},
+		{Title: "Album", Releases: []mb2.Release{
+			testRelease("album", "a", "c", "d"),
+			testRelease("deluxe", "a", "c", "d", "e"),
+		}},
+		{Title: "Compilation", Releases: []mb2.Release{testRelease("compilation", "a", "b", "c")}},
+	}
+	scc := setCoverConfig{TitleOwned: map[string]bool{"d": true}}
+	want := []groupMissing{
+		{Title: "Album", Songs: []missingSong{{"a", 4}, {"c", 3}, {"e", 1}}},
+		{Title: "Single", Songs: []missingSong{{"b", 2}}},
+	}
+	res := missingSongs(groups, scc)
+	if len(res) != len(want) {
+		t.Fatalf(`missingSongs(%v) = %+v, wanted %+v`, groups, res, want)
+	}
+	for i := range want {
+		if res[i].Title != want[i].Title || !slices.Equal(res[i].Songs, want[i].Songs) {
+			t.Errorf(`missingSongs(%v) = %+v, wanted %+v`, groups, res, want)
+		}
+	}
+}
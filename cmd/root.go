@@ -15,9 +15,15 @@ func NewRootCmd() *cobra.Command {
 		Short:   "A command-line tool to aid in collecting music.",
 		Long: "MusicGreed aims to speed up efforts to build a complete digital music " +
 			"collection. This is done by using `setcover` to calculate a collection " +
-			"goal for a music artist, or `remainder` to calculate the set cover on the " +
-			"tracks missing from a current collection (feature to come).",
-		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+			"goal for a music artist, or `remainder` to list the songs missing from a " +
+			"current collection.",
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			// Apply the configuration first, as it may set any of the flags read below.
+			if err := applyConfig(cmd); err != nil {
+				cmd.SilenceUsage = true
+				return err
+			}
+			messages = messageWriter(cmd)
 			// Configure the logger
 			slog.SetDefault(slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelInfo})))
 			if lout, _ := cmd.Flags().GetString("output"); lout != "" {
@@ -35,13 +41,20 @@ func NewRootCmd() *cobra.Command {
 				"command called",
 				"command", os.Args,
 			)
+			return nil
 		},
 	}
 
 	cmd.PersistentFlags().StringP("output", "o", "", "path to log output file")
+	cmd.PersistentFlags().String("config", "", "path to the configuration file (default in the user configuration directory)")
+	cmd.PersistentFlags().String("profile", "", "named profile of flags to apply from the configuration file")
 
 	cmd.AddCommand(
 		NewSetCoverCmd(),
+		NewRemainderCmd(),
+		NewSweepCmd(),
+		NewDiffCmd(),
+		NewWatchCmd(),
 	)
 
 	cmd.CompletionOptions.HiddenDefaultCmd = true
@@ -4,32 +4,39 @@ import (
 	"cmp"
 	"fmt"
 	"log/slog"
+	"math"
 	"slices"
 	"strings"
 	"sync"
+	"time"
 
-	"github.com/adrg/strutil"
-	"github.com/adrg/strutil/metrics"
-	"github.com/frigorific44/musicgreed/beets"
+	"github.com/frigorific44/musicgreed/acoustid"
 	"github.com/frigorific44/musicgreed/concurrency"
+	"github.com/frigorific44/musicgreed/disjointset"
 	"github.com/frigorific44/musicgreed/musicinfo"
 	"github.com/frigorific44/musicgreed/prompt"
+	"github.com/frigorific44/musicgreed/titlematch"
 	"github.com/spf13/cobra"
 	mb2 "go.uploadedlobster.com/musicbrainzws2"
 )
 
 const (
-	tableHeader string = "Contribution | Release(s)"
+	tableHeader  string = "Contribution | Release(s)"
+	daltAll      string = "all"
+	medleysItem  string = "item"
+	medleysCover string = "cover"
+	medleysSkip  string = "ignore"
 )
 
 var (
-	horizontal string = strings.Repeat("—", len(tableHeader))
+	horizontal  string   = strings.Repeat("—", len(tableHeader))
+	medleyModes []string = []string{medleysItem, medleysCover, medleysSkip}
 )
 
 // setcoverCmd represents the setcover command
 func NewSetCoverCmd() *cobra.Command {
 	cmd := &cobra.Command{
-		Use:   `setcover artist`,
+		Use:   `setcover artist...`,
 		Short: "Compute the set cover for the complete song collection of an artist.",
 		Long: "This command computes the minimal set of releases needed to contain every " +
 			"unique track released by an artist. In addition, the unique contribution of " +
@@ -38,60 +45,185 @@ func NewSetCoverCmd() *cobra.Command {
 			"on desired thoroughness." +
 			"\n\nTo discard live and remixed releases:" +
 			"\n\n`musicgreed setcover --dsec=\"live,remix\" artist`" +
-			"\n\nThe previous command can only discard whole releases tagged as " +
-			"mentioned. To discard individual tracks that are parenthesized as an " +
-			"alternate version:" +
+			"\n\nRelease groups may also be selected by primary type, such as albums and EPs only:" +
+			"\n\n`musicgreed setcover --primary=\"album,ep\" --dsec=\"live\" artist`" +
+			"\n\nIndividual releases may be filtered by status, medium format, country, date, " +
+			"and packaging. Releases without a date are discarded when a date range is given. " +
+			"Formats are compared by their whole MusicBrainz names, so \"CD\" doesn't discard SACDs. " +
+			"To only consider official releases since 2000 that aren't cassette or DVD only:" +
+			"\n\n`musicgreed setcover --status=official --since=2000 --dformat=\"Cassette,DVD-Video\" artist`" +
+			"\n\nFor arbitrary selection rules, a filter expression is evaluated on every " +
+			"track, which is kept only if the expression holds. Fields of the release group " +
+			"(group.title, group.primary, group.secondary), release (release.id, release.title, " +
+			"release.disambiguation, release.status, release.country, release.date, release.year, " +
+			"release.formats, release.packaging, release.barcode, release.artist), and track " +
+			"(track.title, track.number, track.position, track.length, track.artist, track.video, " +
+			"track.recording) may be compared with ==, !=, <, <=, >, >=, matched against regular " +
+			"expressions with ~ and !~, tested for membership with in, and combined with and, " +
+			"or, not, and parentheses:" +
+			"\n\n`musicgreed setcover --filter='release.country in [\"US\",\"GB\"] and not track.title ~ \"(?i)remaster\"' artist`" +
+			"\n\nThe previous commands can only discard whole releases. To discard " +
+			"individual tracks that are parenthesized as an alternate version:" +
 			"\n\n`musicgreed setcover --dalt artist`" +
+			"\n\nAlternate versions are classified as live, remix, acoustic, instrumental, demo, " +
+			"radio-edit, extended, remaster, or other, and only some kinds may be discarded. To " +
+			"discard remixes and live versions while keeping acoustic versions:" +
+			"\n\n`musicgreed setcover --dalt=remix,live artist`" +
+			"\n\nMedleys, found by their MusicBrainz work relationships or titles such as " +
+			"\"Medley: A - B\" and \"A / B\", count as their own item by default. They may " +
+			"instead count as covering each of their songs, or be ignored:" +
+			"\n\n`musicgreed setcover --medleys=cover artist`" +
+			"\n\nTitles are compared once normalized: letters are lowercased and stripped of " +
+			"diacritics, Cyrillic and Greek are transliterated, featured artists are dropped, " +
+			"punctuation and spacing are made uniform, and Roman numerals numbering a part " +
+			"become numbers. Titles normalizing alike are taken as equal, and other titles " +
+			"are alike when their similarity by the chosen metric (levenshtein, jaro-winkler, " +
+			"or token-set, which disregards word order) exceeds the threshold:" +
+			"\n\n`musicgreed setcover --metric=jaro-winkler --threshold=0.9 artist`" +
+			"\n\nAlike titles are then compared by the lengths of their tracks. Titles whose " +
+			"median lengths are within the tolerance are taken as equal, those whose closest " +
+			"lengths are further apart than the length threshold as different, and you are " +
+			"only asked about the rest:" +
+			"\n\n`musicgreed setcover --length-tolerance=2s --length-threshold=30s artist`" +
+			"\n\nTitles whose recordings share an AcoustID fingerprint are the same audio, and " +
+			"are taken as equal without asking even when titled differently. AcoustIDs may be " +
+			"read from a local CSV dump with acoustid and mbid columns, or looked up from the " +
+			"AcoustID web service or a server standing in for it. The client key may also be " +
+			"set with the " + acoustIDKeyEnv + " environment variable:" +
+			"\n\n`musicgreed setcover --acoustid-dump=acoustid-track-mbid.csv.gz artist`" +
+			"\n\n`musicgreed setcover --acoustid-key=key artist`" +
+			"\n\nTitles taken as equal are listed, and each is counted under a canonical title: " +
+			"the longest by default, or the one found on the most tracks, the shortest, or the " +
+			"one from the earliest release:" +
+			"\n\n`musicgreed setcover --canonical=most-frequent artist`" +
+			"\n\nFlags used on every run can be kept in a YAML configuration file, by default " +
+			"config.yaml in the musicgreed directory of the user configuration directory " +
+			"(such as ~/.config/musicgreed). Flags under `defaults` always apply, and flags " +
+			"under a named profile apply when it is selected with --profile. Flags given on " +
+			"the command line take precedence:" +
+			"\n\n```\ndefaults:\n  dalt: true\nprofiles:\n  strict:\n    dsec: [live, remix]\n    status: official\n    format: json\n```" +
+			"\n\n`musicgreed setcover --profile strict artist`" +
+			"\n\nAlternate versions are recognized by terms such as live, remix, or acoustic, in " +
+			"English, French, German, Japanese, Russian, and Spanish. More terms can be added " +
+			"by language under the defaults or a profile, along with terms such as intro which " +
+			"never mark an alternate version:" +
+			"\n\n```\ndefaults:\n  alternate-terms:\n    Spanish: [directo]\n  not-alternate-terms:\n    Spanish: [coda]\n```" +
+			"\n\nThere are often several minimal set covers of the same size. They are " +
+			"summarized by the releases common to every cover, followed by slots to pick " +
+			"interchangeable releases from. To list every cover in full instead:" +
+			"\n\n`musicgreed setcover --all-covers artist`" +
+			"\n\nTied covers may be ranked by criteria, each breaking the ties of those before " +
+			"it: the fewest tracks in all (tracks), the latest average release date (recent), " +
+			"the most releases from a country (country:XX) or in a format (format:name) or " +
+			"available as digital media (digital), or the lowest total price (cost), read from " +
+			"a CSV file with mbid and price columns. Ranked covers are listed in full, best " +
+			"first, in place of the summary, as with --all-covers. To show only the best " +
+			"three covers:" +
+			"\n\n`musicgreed setcover --rank-by=country:GB,digital,tracks --top=3 artist`" +
+			"\n\n`musicgreed setcover --rank-by=cost --prices=prices.csv --top=1 artist`" +
+			"\n\nTo buy the best cover a release at a time, the plan flag orders its releases " +
+			"so each adds the most songs not yet covered, with the percentage of songs covered " +
+			"after each purchase. In remainder mode, songs already owned count from the start:" +
+			"\n\n`musicgreed setcover --plan artist`" +
+			"\n\nTo see why each recommended release was chosen, the explain flag lists the " +
+			"tracks found on no other release, and the releases that could take its place in " +
+			"a cover:" +
+			"\n\n`musicgreed setcover --explain artist`" +
 			"\n\nIf you maintain your library with the beets library manager, you can exclude " +
 			"your collection from `setcover` with the remainder flag:" +
-			"\n\n`musicgreed setcover -r artist`",
-		Args: cobra.ExactArgs(1),
+			"\n\n`musicgreed setcover -r artist`" +
+			"\n\nIn remainder mode, the tracks owned and missing from each release group are " +
+			"reported, fully owned releases are left out of the cover, and the tracks already " +
+			"owned on each recommended release are listed." +
+			"\n\nA Jellyfin-compatible media server can serve as the library instead, " +
+			"provided its items carry MusicBrainz provider IDs. The API key may also be " +
+			"set with the " + jellyfinKeyEnv + " environment variable:" +
+			"\n\n`musicgreed setcover -r --library=jellyfin --jellyfin-url=http://localhost:8096 artist`" +
+			"\n\nGiven several artists, one set cover is computed over the union of their " +
+			"songs, so a split or compilation release may count toward each of them. To " +
+			"also consider releases by others where the artists are credited on tracks:" +
+			"\n\n`musicgreed setcover --with-collaborations artist1 artist2`" +
+			"\n\nTo also consider the artist's recordings on soundtracks, compilations, and " +
+			"other artists' releases, where only the artist's own recordings are counted:" +
+			"\n\n`musicgreed setcover --appearances artist`" +
+			"\n\nTo discard guest and bonus tracks by other artists, as well as tracks where " +
+			"the artist is only featured:" +
+			"\n\n`musicgreed setcover --credited-only --exclude-featured artist`",
+		Args: cobra.MinimumNArgs(1),
 		Run: func(cmd *cobra.Command, args []string) {
-			scc := setCoverConfig{setCoverFlags: packageSetCoverFlags(cmd)}
+			flags, err := packageSetCoverFlags(cmd)
+			if err != nil {
+				fmt.Println(err)
+				return
+			}
+			format, err := outputFormat(cmd)
+			if err != nil {
+				fmt.Println(err)
+				return
+			}
+			library, err := openLibrary(flags)
+			if err != nil {
+				fmt.Println(err)
+				return
+			}
+			scc := setCoverConfig{setCoverFlags: flags, MusicLibrary: library}
 			client, stop := musicinfo.NewMGClient()
 			defer stop()
 
-			mbid, idErr := artistMBID(client, args[0])
-			if idErr != nil {
-				fmt.Println("Artist ID could not be retrieved")
-				return
+			for _, arg := range args {
+				mbid, idErr := artistMBID(client, arg)
+				if idErr != nil {
+					fmt.Fprintf(messages, "Artist ID could not be retrieved for %q\n", arg)
+					return
+				}
+				scc.ArtistMBIDs = append(scc.ArtistMBIDs, mbid)
 			}
-			scc.ArtistMBID = mbid
 
-			fmt.Println("Retrieving music...")
-			var status string
-			if scc.Official {
-				status = "official"
-			}
-			groups, err := musicinfo.ReleaseGroupsByArtist(client, scc.ArtistMBID, status)
+			groups, releases, covers, err := computeSetCovers(client, &scc)
 			if err != nil {
-				fmt.Println(err)
+				fmt.Fprintln(messages, err)
+				return
+			}
+			var owned []groupOwnership
+			if scc.Remainder {
+				owned = ownership(groups, scc)
+			}
+			var explained []releaseExplanation
+			if explain, _ := cmd.Flags().GetBool("explain"); explain {
+				explained = explainCovers(releases, covers, scc)
+			}
+			var plan *purchasePlan
+			if planned, _ := cmd.Flags().GetBool("plan"); planned && len(covers) > 0 {
+				p := newPurchasePlan(groups, covers[0], scc)
+				plan = &p
+			}
+			if format == formatJSON {
+				result := newSetCoverResult(releases, covers, scc)
+				result.Ownership = owned
+				result.Explanations = explained
+				result.Plan = plan
+				if err := writeJSON(result); err != nil {
+					fmt.Fprintln(messages, err)
+				}
 				return
 			}
 
-			// Pre-processing
-			filtered := filterBySecondaryType(groups, scc)
-			learnTracks(filtered, &scc)
-			slog.Debug(
-				"Set Cover Configuration",
-				"Config", scc)
-			// remove duplicates
-			var releases []mb2.Release
-			for _, rg := range filtered {
-				releases = append(releases, uniqueReleases(rg.Releases, scc)...)
-			}
-
-			fmt.Println("Calculating set covers...")
-			covers := setcovers(releases, scc)
-			for i, msc := range covers {
-				contribution := contributions(msc, scc)
-				slices.SortFunc(contribution, func(a, b coverContribution) int {
-					conComp := cmp.Compare(a.Contribution, b.Contribution)
-					if conComp == 0 {
-						return cmp.Compare(a.Title, b.Title)
-					}
-					return -1 * conComp
-				})
+			if scc.Remainder {
+				printOwnership(owned)
+			}
+			printTitleGroups(scc.TitleGroups)
+			shown := covers
+			// Ranked covers are listed in full, best first, as the summary has no order.
+			ranked := len(scc.RankBy) > 0 || scc.Top > 0
+			if allCovers, _ := cmd.Flags().GetBool("all-covers"); !allCovers && !ranked && len(covers) > 1 {
+				printCoverSummary(summarizeCovers(covers))
+				if scc.Remainder {
+					printSummaryOwned(covers, scc)
+				}
+				shown = nil
+			}
+			for i, msc := range shown {
+				contribution := sortedContributions(msc, scc)
 				fmt.Print("\n> Set Cover ", i)
 				fmt.Println(",", len(contribution), "releases")
 				fmt.Println(horizontal)
@@ -109,43 +241,264 @@ func NewSetCoverCmd() *cobra.Command {
 				}
 				fmt.Println("\nRelease Titles:")
 				fmt.Println(strings.Join(titles, "; "))
+				if scc.Remainder {
+					fmt.Println("\nAlready Owned:")
+					for _, c := range contribution {
+						if len(c.Owned) > 0 {
+							fmt.Printf("%v: %v\n", c.Title, strings.Join(c.Owned, "; "))
+						}
+					}
+				}
 				slog.Debug(
 					fmt.Sprint("set cover result", i),
 					"set cover", contribution)
 			}
+			if plan != nil {
+				printPurchasePlan(*plan)
+			}
+			printExplanations(explained, len(covers))
 		},
 	}
 
+	addFilterFlags(cmd)
+	addMatchFlags(cmd)
+	cmd.Flags().BoolP("remainder", "r", false, "requires a music library; calculates on the remainder after library tracks")
+	addLibraryFlags(cmd)
+	addOutputFlags(cmd)
+	cmd.Flags().StringSlice("rank-by", []string{},
+		"order tied set covers by criteria, in precedence (tracks, recent, country:XX, format:name, digital, cost)",
+	)
+	cmd.Flags().String("prices", "", "CSV file of release prices, with mbid and price columns, for ranking by cost")
+	cmd.Flags().Int("top", 0, "only show the best ranked set covers, if positive")
+	cmd.Flags().Bool("all-covers", false, "list every minimal set cover in full rather than summarizing them")
+	cmd.Flags().Bool("plan", false, "order the releases of the best set cover by the new songs each adds")
+	cmd.Flags().Bool("explain", false, "explain each chosen release by its essential tracks and substitutes")
+
+	return cmd
+}
+
+func addFilterFlags(cmd *cobra.Command) {
 	cmd.Flags().StringSlice("dsec", []string{},
 		"discard MusicBrainz secondary release group types (https://musicbrainz.org/doc/Release_Group/Type)",
 	)
-	cmd.Flags().Bool("dalt", false, "discard parenthesized alternate tracks (acoustic, remix, etc.)")
+	cmd.Flags().StringSlice("primary", []string{},
+		"only MusicBrainz primary release group types (album, single, ep, broadcast, other)",
+	)
+	cmd.Flags().StringSlice("dprimary", []string{}, "discard MusicBrainz primary release group types")
+	cmd.Flags().StringSlice("dalt", []string{}, "discard parenthesized alternate tracks of these kinds (live, remix, acoustic, instrumental, demo, radio-edit, extended, remaster, other), or all kinds when given alone")
+	cmd.Flags().Lookup("dalt").NoOptDefVal = daltAll
+	cmd.Flags().String("medleys", medleysItem, "how medley tracks count: as their own item, as covering their songs, or ignored (item, cover, ignore)")
 	cmd.Flags().Bool("official", false, "only official releases (https://musicbrainz.org/doc/Release#Status)")
-	cmd.Flags().BoolP("remainder", "r", false, "requires a beets music library; calculates on the remainder after library tracks")
+	cmd.Flags().MarkDeprecated("official", "use --status=official instead")
+	cmd.Flags().StringSlice("status", []string{},
+		"only releases of these statuses (official, promotion, bootleg, pseudo-release)",
+	)
+	cmd.Flags().StringSlice("dformat", []string{}, "discard releases whose media are all of these MusicBrainz formats (CD, Cassette, Digital Media, etc.)")
+	cmd.Flags().StringSlice("country", []string{}, "only releases from these countries or areas (US, GB, XW, Japan, etc.)")
+	cmd.Flags().String("since", "", "only releases dated on or after this date (YYYY, YYYY-MM, or YYYY-MM-DD)")
+	cmd.Flags().String("until", "", "only releases dated on or before this date (YYYY, YYYY-MM, or YYYY-MM-DD)")
+	cmd.Flags().StringSlice("dpackaging", []string{}, "discard releases of these packaging types (jewel case, digipak, etc.)")
+	cmd.Flags().String("filter", "", "only tracks for which this expression holds (see the setcover documentation)")
+	cmd.Flags().Bool("with-collaborations", false, "include releases by others where the artist is credited on tracks")
+	cmd.Flags().Bool("appearances", false, "include the artist's recordings on other releases (soundtracks, compilations, etc.)")
+	cmd.Flags().Bool("credited-only", false, "discard tracks not credited to the artist")
+	cmd.Flags().Bool("exclude-featured", false, "discard tracks where the artist is only credited as featured")
+}
 
-	return cmd
+func addMatchFlags(cmd *cobra.Command) {
+	cmd.Flags().Duration("length-tolerance", 3*time.Second,
+		"similar titles whose median track lengths are this close are equal without asking (0 to always ask)",
+	)
+	cmd.Flags().Duration("length-threshold", 20*time.Second,
+		"similar titles whose recordings differ in length by more than this are not equal (0 to always ask)",
+	)
+	cmd.Flags().String("metric", titlematch.Levenshtein, "similarity metric for comparing titles (levenshtein, jaro-winkler, token-set)")
+	cmd.Flags().Float64("threshold", 0, "similarity above which titles are compared further (0 for the metric's default)")
+	cmd.Flags().String("canonical", canonicalLongest, "title substituted for its equivalents (longest, most-frequent, shortest, earliest)")
+	cmd.Flags().String("acoustid-dump", "", "CSV dump of AcoustIDs by recording; titles sharing an AcoustID are equal")
+	cmd.Flags().String("acoustid-server", "", "URL of an AcoustID server to look up AcoustIDs by recording (default "+acoustid.DefaultServerURL+" with a key)")
+	cmd.Flags().String("acoustid-key", "", "client key for the AcoustID server")
+}
+
+func addLibraryFlags(cmd *cobra.Command) {
+	cmd.Flags().String("library", libraryBeets, "music library to use with remainder (beets, jellyfin)")
+	cmd.Flags().String("jellyfin-url", "", "base URL of the Jellyfin server")
+	cmd.Flags().String("jellyfin-key", "", "API key for the Jellyfin server")
 }
 
 type setCoverFlags struct {
-	DSec      []string
-	DAlt      bool
-	Official  bool
-	Remainder bool
+	DSec               []string
+	Primary            []string
+	DPrimary           []string
+	DAlt               []string
+	AltTerms           map[string][]string `json:",omitempty"`
+	NotAltTerms        map[string][]string `json:",omitempty"`
+	Medleys            string
+	Status             []string
+	DFormat            []string
+	Country            []string
+	Since              string
+	Until              string
+	DPackaging         []string
+	Filter             string
+	WithCollaborations bool
+	Appearances        bool
+	CreditedOnly       bool
+	ExcludeFeatured    bool
+	Metric             string
+	Threshold          float64
+	Canonical          string
+	LengthTolerance    time.Duration
+	LengthThreshold    time.Duration
+	AcoustIDDump       string
+	AcoustIDServer     string
+	AcoustIDKey        string `json:"-"`
+	RankBy             []string
+	Prices             string
+	Top                int
+	Remainder          bool
+	Library            string
+	JellyfinURL        string
+	JellyfinKey        string `json:"-"`
 }
 
 type setCoverConfig struct {
 	setCoverFlags
-	TitleSub    map[string]string
-	TitleIgnore map[string]bool
-	ArtistMBID  mb2.MBID
+	TitleSub           map[string]string
+	TitleGroups        []titleGroup
+	TitleIgnore        map[string]bool
+	TitleOwned         map[string]bool
+	ArtistMBIDs        []mb2.MBID
+	AppearanceReleases map[mb2.MBID]bool
+	ArtistRecordings   map[mb2.MBID]bool
+	MusicLibrary       musicLibrary
+	SinceDate          time.Time
+	UntilDate          time.Time
 }
 
-func packageSetCoverFlags(cmd *cobra.Command) setCoverFlags {
+// Packages the command's flags, those not given on the command line having been
+// taken from the configuration file before the command ran.
+func packageSetCoverFlags(cmd *cobra.Command) (setCoverFlags, error) {
 	dSec, _ := cmd.Flags().GetStringSlice("dsec")
-	dAlt, _ := cmd.Flags().GetBool("dalt")
+	primary, _ := cmd.Flags().GetStringSlice("primary")
+	dPrimary, _ := cmd.Flags().GetStringSlice("dprimary")
+	dAlt, _ := cmd.Flags().GetStringSlice("dalt")
+	dAlt, err := altKinds(dAlt)
+	if err != nil {
+		return setCoverFlags{}, err
+	}
+	medleys, _ := cmd.Flags().GetString("medleys")
 	official, _ := cmd.Flags().GetBool("official")
+	status, _ := cmd.Flags().GetStringSlice("status")
+	if official && !containsFold(status, "official") {
+		status = append(status, "official")
+	}
+	dFormat, _ := cmd.Flags().GetStringSlice("dformat")
+	country, _ := cmd.Flags().GetStringSlice("country")
+	since, _ := cmd.Flags().GetString("since")
+	until, _ := cmd.Flags().GetString("until")
+	dPackaging, _ := cmd.Flags().GetStringSlice("dpackaging")
+	filter, _ := cmd.Flags().GetString("filter")
+	withCollaborations, _ := cmd.Flags().GetBool("with-collaborations")
+	appearances, _ := cmd.Flags().GetBool("appearances")
+	creditedOnly, _ := cmd.Flags().GetBool("credited-only")
+	excludeFeatured, _ := cmd.Flags().GetBool("exclude-featured")
+	metric, _ := cmd.Flags().GetString("metric")
+	threshold, _ := cmd.Flags().GetFloat64("threshold")
+	canonical, _ := cmd.Flags().GetString("canonical")
+	lengthTolerance, _ := cmd.Flags().GetDuration("length-tolerance")
+	lengthThreshold, _ := cmd.Flags().GetDuration("length-threshold")
+	acoustIDDump, _ := cmd.Flags().GetString("acoustid-dump")
+	acoustIDServer, _ := cmd.Flags().GetString("acoustid-server")
+	acoustIDKey, _ := cmd.Flags().GetString("acoustid-key")
+	rankBy, _ := cmd.Flags().GetStringSlice("rank-by")
+	prices, _ := cmd.Flags().GetString("prices")
+	top, _ := cmd.Flags().GetInt("top")
 	remainder, _ := cmd.Flags().GetBool("remainder")
-	return setCoverFlags{DSec: dSec, DAlt: dAlt, Official: official, Remainder: remainder}
+	library, _ := cmd.Flags().GetString("library")
+	jellyfinURL, _ := cmd.Flags().GetString("jellyfin-url")
+	jellyfinKey, _ := cmd.Flags().GetString("jellyfin-key")
+	flags := setCoverFlags{
+		DSec:               dSec,
+		Primary:            primary,
+		DPrimary:           dPrimary,
+		DAlt:               dAlt,
+		AltTerms:           configAltTerms,
+		NotAltTerms:        configNotAltTerms,
+		Medleys:            medleys,
+		Status:             status,
+		DFormat:            dFormat,
+		Country:            country,
+		Since:              since,
+		Until:              until,
+		DPackaging:         dPackaging,
+		Filter:             filter,
+		WithCollaborations: withCollaborations,
+		Appearances:        appearances,
+		CreditedOnly:       creditedOnly,
+		ExcludeFeatured:    excludeFeatured,
+		Metric:             metric,
+		Threshold:          threshold,
+		Canonical:          canonical,
+		LengthTolerance:    lengthTolerance,
+		LengthThreshold:    lengthThreshold,
+		AcoustIDDump:       acoustIDDump,
+		AcoustIDServer:     acoustIDServer,
+		AcoustIDKey:        acoustIDKey,
+		RankBy:             rankBy,
+		Prices:             prices,
+		Top:                top,
+		Remainder:          remainder,
+		Library:            library,
+		JellyfinURL:        jellyfinURL,
+		JellyfinKey:        jellyfinKey,
+	}
+	if _, _, err := titleMetric(flags); err != nil {
+		return flags, err
+	}
+	if !slices.Contains(medleyModes, flags.Medleys) {
+		return flags, fmt.Errorf(`unknown medley handling %q, expected one of %v`, flags.Medleys, medleyModes)
+	}
+	if !slices.Contains(canonicalRules, flags.Canonical) {
+		return flags, fmt.Errorf(`unknown canonical title rule %q, expected one of %v`, flags.Canonical, canonicalRules)
+	}
+	for _, criterion := range flags.RankBy {
+		if _, err := rankCriterion(criterion); err != nil {
+			return flags, err
+		}
+	}
+	if slices.Contains(flags.RankBy, rankCost) && flags.Prices == "" {
+		return flags, fmt.Errorf(`ranking by cost requires a --prices file`)
+	}
+	if flags.Top < 0 {
+		return flags, fmt.Errorf(`--top must not be negative, got %v`, flags.Top)
+	}
+	return flags, nil
+}
+
+// Expands the kinds of alternate tracks to discard, where "all" or "true"
+// stands for every kind and "false" for none.
+func altKinds(kinds []string) ([]string, error) {
+	var expanded []string
+	for _, k := range kinds {
+		k = strings.ToLower(strings.TrimSpace(k))
+		switch {
+		case k == "" || k == "false":
+		case k == daltAll || k == "true":
+			all := make([]string, len(musicinfo.AltKinds))
+			for i, kind := range musicinfo.AltKinds {
+				all[i] = string(kind)
+			}
+			return all, nil
+		case slices.Contains(musicinfo.AltKinds, musicinfo.AltKind(k)):
+			if !slices.Contains(expanded, k) {
+				expanded = append(expanded, k)
+			}
+		default:
+			return nil, fmt.Errorf(`unknown alternate track kind %q for --dalt, expected one of %v or %v`, k, musicinfo.AltKinds, daltAll)
+		}
+	}
+	return expanded, nil
 }
 
 func artistMBID(client musicinfo.MGClient, query string) (mb2.MBID, error) {
@@ -165,31 +518,115 @@ func artistMBID(client musicinfo.MGClient, query string) (mb2.MBID, error) {
 	}
 }
 
-func filterBySecondaryType(groups []mb2.ReleaseGroup, scc setCoverConfig) []mb2.ReleaseGroup {
-	var filtered []mb2.ReleaseGroup
-ReleaseGroupLoop:
-	for _, rg := range groups {
-		for _, dType := range scc.DSec {
-			for _, sType := range rg.SecondaryTypes {
-				if strings.EqualFold(dType, sType) {
-					continue ReleaseGroupLoop
-				}
+// Retrieves the release groups of the configured artists, filtered and with tracks learned.
+func artistReleaseGroups(client musicinfo.MGClient, scc *setCoverConfig) ([]mb2.ReleaseGroup, error) {
+	if err := validateReleaseFilters(*scc); err != nil {
+		return nil, err
+	}
+	// Only a single status can be requested, the rest are filtered afterward.
+	var status string
+	if len(scc.Status) == 1 {
+		status = strings.ToLower(scc.Status[0])
+	}
+	includes := musicinfo.ReleaseIncludes(scc.Medleys == medleysCover || scc.Medleys == medleysSkip)
+	var groupLists [][]mb2.ReleaseGroup
+	for _, id := range scc.ArtistMBIDs {
+		groups, err := musicinfo.ReleaseGroupsByArtist(client, id, status, includes)
+		if err != nil {
+			return nil, err
+		}
+		groupLists = append(groupLists, groups)
+		if scc.WithCollaborations {
+			collaborations, err := musicinfo.ReleaseGroupsByTrackArtist(client, id, status, includes)
+			if err != nil {
+				return nil, err
+			}
+			groupLists = append(groupLists, collaborations)
+		}
+	}
+	groups := musicinfo.MergeReleaseGroups(groupLists...)
+	if scc.Appearances {
+		scc.AppearanceReleases = make(map[mb2.MBID]bool)
+		scc.ArtistRecordings = make(map[mb2.MBID]bool)
+		var appearanceLists [][]mb2.ReleaseGroup
+		for _, id := range scc.ArtistMBIDs {
+			appearances, recordings, err := musicinfo.ReleaseGroupsByAppearance(client, id, status, includes, groups)
+			if err != nil {
+				return nil, err
+			}
+			for rec := range recordings {
+				scc.ArtistRecordings[rec] = true
 			}
+			appearanceLists = append(appearanceLists, appearances)
 		}
-		filtered = append(filtered, rg)
+		appearances := musicinfo.MergeReleaseGroups(appearanceLists...)
+		for _, rg := range appearances {
+			for _, r := range rg.Releases {
+				scc.AppearanceReleases[r.ID] = true
+			}
+		}
+		groups = musicinfo.MergeReleaseGroups(groups, appearances)
+	}
+
+	// Pre-processing
+	filtered, err := filterByExpression(filterReleases(filterReleaseGroups(groups, *scc), *scc), *scc)
+	if err != nil {
+		return nil, err
 	}
-	return filtered
+	learnTracks(filtered, scc)
+	slog.Debug(
+		"Set Cover Configuration",
+		"Config", *scc)
+	return filtered, nil
 }
 
-func setcovers(releases []mb2.Release, scc setCoverConfig) [][]mb2.Release {
+// Retrieves the music of the configured artists and computes their ranked set
+// covers, along with the release groups and releases they were computed from.
+func computeSetCovers(client musicinfo.MGClient, scc *setCoverConfig) ([]mb2.ReleaseGroup, []mb2.Release, [][]mb2.Release, error) {
+	fmt.Fprintln(messages, "Retrieving music...")
+	filtered, err := artistReleaseGroups(client, scc)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	releases := coverReleases(filtered, *scc)
+
+	fmt.Fprintln(messages, "Calculating set covers...")
+	prices, err := readPrices(scc.Prices)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	covers, err := rankCovers(setcovers(releases, *scc), scc.RankBy, prices, scc.Top)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return filtered, releases, covers, nil
+}
+
+// Returns the releases to calculate the set cover on, without duplicates.
+func coverReleases(groups []mb2.ReleaseGroup, scc setCoverConfig) []mb2.Release {
+	var releases []mb2.Release
+	for _, rg := range groups {
+		releases = append(releases, uniqueReleases(rg.Releases, scc)...)
+	}
+	return dropFullyOwned(releases, scc)
+}
+
+// Maps each track title to the distinct indices of the releases carrying it.
+func buildTrackMap(releases []mb2.Release, scc setCoverConfig) map[string][]int {
 	trackMap := make(map[string][]int)
 	for i, r := range releases {
 		for _, t := range releaseTrackTitles(r, scc) {
-			trackMap[t] = append(trackMap[t], i)
+			// A title repeated on the release, such as a reprise, maps to it once.
+			if rs := trackMap[t]; len(rs) == 0 || rs[len(rs)-1] != i {
+				trackMap[t] = append(rs, i)
+			}
 		}
 	}
+	return trackMap
+}
 
-	combinations := minimalCombinations(trackMap)
+func setcovers(releases []mb2.Release, scc setCoverConfig) [][]mb2.Release {
+	combinations := minimalCombinations(buildTrackMap(releases, scc))
 
 	var covers [][]mb2.Release
 	for _, p := range combinations {
@@ -204,50 +641,43 @@ func setcovers(releases []mb2.Release, scc setCoverConfig) [][]mb2.Release {
 
 func uniqueReleases(releases []mb2.Release, scc setCoverConfig) []mb2.Release {
 	// Gather each release's track titles, sorted alphabetically.
-	rTracks := make(map[int][]string)
+	rTracks := make([][]string, len(releases))
 	for i, r := range releases {
 		rTracks[i] = releaseTrackTitles(r, scc)
 	}
-	var groups [][]mb2.Release
-	// Each loop, form a group of releases with identical track titles.
-	for len(rTracks) > 0 {
-		ref := -1
-		var refVal []string
-		var group []mb2.Release
-		for k, v := range rTracks {
-			if ref < 0 {
-				ref = k
-				refVal = v
-			} else {
-				if len(refVal) != len(v) {
-					continue
-				}
-				isEqual := true
-				for i, t := range refVal {
-					if t != v[i] {
-						isEqual = false
-						break
-					}
-				}
-				if isEqual {
-					group = append(group, releases[k])
-					delete(rTracks, k)
-				}
+	grouped := make([]bool, len(releases))
+	var toReturn []mb2.Release
+	// Each loop, form a group of releases with identical track titles, in the
+	// order they're first found.
+	for i := range releases {
+		if grouped[i] {
+			continue
+		}
+		group := []mb2.Release{releases[i]}
+		for j := i + 1; j < len(releases); j++ {
+			if !grouped[j] && slices.Equal(rTracks[i], rTracks[j]) {
+				grouped[j] = true
+				group = append(group, releases[j])
 			}
 		}
-		group = append(group, releases[ref])
-		delete(rTracks, ref)
-		groups = append(groups, group)
-	}
-	var toReturn []mb2.Release
-	// Select one release to reperesent each group.
-	// TODO: Way to set release region preference, or to somehow collate titles the releases may be known under
-	for _, g := range groups {
-		toReturn = append(toReturn, g[0])
+		// Select one release to represent each group, the same on every run.
+		// TODO: Way to set release region preference, or to somehow collate titles the releases may be known under
+		toReturn = append(toReturn, slices.MinFunc(group, compareRepresentatives))
 	}
 	return toReturn
 }
 
+// Orders releases by date, the earliest first and undated last, then by MBID.
+func compareRepresentatives(a, b mb2.Release) int {
+	if a.Date.IsZero() != b.Date.IsZero() {
+		if a.Date.IsZero() {
+			return 1
+		}
+		return -1
+	}
+	return cmp.Or(a.Date.Time.Compare(b.Date.Time), cmp.Compare(a.ID, b.ID))
+}
+
 func minimalCombinations(trackMap map[string][]int) [][]int {
 	var combinations [][]int
 	var wg sync.WaitGroup
@@ -331,9 +761,23 @@ type coverContribution struct {
 	Title        string
 	ID           mb2.MBID
 	Tracks       []string
+	Owned        []string
 	Contribution int
 }
 
+// Returns the contributions of the set cover, from greatest to least.
+func sortedContributions(setcover []mb2.Release, scc setCoverConfig) []coverContribution {
+	contribution := contributions(setcover, scc)
+	slices.SortFunc(contribution, func(a, b coverContribution) int {
+		conComp := cmp.Compare(a.Contribution, b.Contribution)
+		if conComp == 0 {
+			return cmp.Compare(a.Title, b.Title)
+		}
+		return -1 * conComp
+	})
+	return contribution
+}
+
 func contributions(setcover []mb2.Release, scc setCoverConfig) []coverContribution {
 	contributions := make([]coverContribution, len(setcover))
 
@@ -359,6 +803,7 @@ func contributions(setcover []mb2.Release, scc setCoverConfig) []coverContributi
 			Title:        release.Title,
 			ID:           release.ID,
 			Tracks:       tracks,
+			Owned:        releaseOwnedTitles(release, scc),
 			Contribution: contribution}
 	}
 
@@ -367,160 +812,423 @@ func contributions(setcover []mb2.Release, scc setCoverConfig) []coverContributi
 
 func releaseTrackTitles(release mb2.Release, scc setCoverConfig) []string {
 	var tracks []string
+	for _, t := range releaseTracks(release, scc) {
+		if title, ok := trackTitle(t, scc); ok && !scc.TitleOwned[title] {
+			tracks = append(tracks, title)
+		}
+	}
+	slices.Sort(tracks)
+	return tracks
+}
+
+// Returns the titles of the release's tracks already in the library.
+func releaseOwnedTitles(release mb2.Release, scc setCoverConfig) []string {
+	var tracks []string
+	for _, t := range releaseTracks(release, scc) {
+		if title, ok := trackTitle(t, scc); ok && scc.TitleOwned[title] {
+			tracks = append(tracks, title)
+		}
+	}
+	slices.Sort(tracks)
+	return slices.Compact(tracks)
+}
+
+// Returns the tracks of the release that belong to the configured artists. Only
+// the artists' own recordings are taken from releases they merely appear on.
+func releaseTracks(release mb2.Release, scc setCoverConfig) []mb2.Track {
+	var tracks []mb2.Track
 	for _, m := range release.Media {
 		for _, t := range m.Tracks {
-			if scc.TitleIgnore[t.Title] || t.Recording.IsVideo {
+			if scc.AppearanceReleases[release.ID] && !scc.ArtistRecordings[t.Recording.ID] {
 				continue
 			}
-			if sub, ok := scc.TitleSub[t.Title]; ok {
-				tracks = append(tracks, sub)
-			} else {
-				tracks = append(tracks, t.Title)
+			credit := trackCredit(release, t)
+			if scc.CreditedOnly && !musicinfo.CreditsArtist(credit, scc.ArtistMBIDs...) {
+				continue
+			}
+			if scc.ExcludeFeatured && musicinfo.FeaturesArtist(credit, scc.ArtistMBIDs...) {
+				continue
+			}
+			if scc.Medleys == medleysCover || scc.Medleys == medleysSkip {
+				if parts := musicinfo.MedleyParts(t); parts != nil {
+					if scc.Medleys == medleysCover {
+						tracks = append(tracks, medleyTracks(t, parts)...)
+					}
+					continue
+				}
 			}
+			tracks = append(tracks, t)
 		}
 	}
-	slices.Sort(tracks)
 	return tracks
 }
 
-// Cleans the title for comparability without changing the meaning
-func CleanTitle(title string) string {
-	title = strings.ToLower(title)
-	title = strings.ReplaceAll(title, "&", "and")
-	return strings.Map(func(r rune) rune {
-		switch r {
-		case '’':
-			return '\''
-		case ',':
-			return -1
+// Splits a medley track into a track for each of its songs. The songs share the
+// medley's track ID, so owning the medley owns each song, but none of its
+// recording, as the songs are only parts of it.
+func medleyTracks(t mb2.Track, parts []string) []mb2.Track {
+	tracks := make([]mb2.Track, len(parts))
+	for i, part := range parts {
+		tracks[i] = mb2.Track{
+			ID:           t.ID,
+			Title:        part,
+			Number:       t.Number,
+			Position:     t.Position,
+			ArtistCredit: t.ArtistCredit,
+			Recording:    mb2.Recording{Title: part, IsVideo: t.Recording.IsVideo, ArtistCredit: t.Recording.ArtistCredit},
+		}
+	}
+	return tracks
+}
+
+// Returns the most specific artist credit available for the track.
+func trackCredit(release mb2.Release, t mb2.Track) mb2.ArtistCredit {
+	if len(t.ArtistCredit) > 0 {
+		return t.ArtistCredit
+	}
+	if len(t.Recording.ArtistCredit) > 0 {
+		return t.Recording.ArtistCredit
+	}
+	return release.ArtistCredit
+}
+
+// Returns the substituted title of the track, and whether the track is considered at all.
+func trackTitle(t mb2.Track, scc setCoverConfig) (string, bool) {
+	if scc.TitleIgnore[t.Title] || t.Recording.IsVideo {
+		return "", false
+	}
+	if sub, ok := scc.TitleSub[t.Title]; ok {
+		return sub, true
+	}
+	return t.Title, true
+}
+
+// Removes releases with no tracks left to collect.
+func dropFullyOwned(releases []mb2.Release, scc setCoverConfig) []mb2.Release {
+	var kept []mb2.Release
+	for _, r := range releases {
+		if len(releaseTrackTitles(r, scc)) > 0 {
+			kept = append(kept, r)
+		}
+	}
+	return kept
+}
+
+type groupOwnership struct {
+	Title   string
+	ID      mb2.MBID
+	Owned   int
+	Missing int
+}
+
+// Counts the unique titles of each release group already owned or missing from the library.
+func ownership(groups []mb2.ReleaseGroup, scc setCoverConfig) []groupOwnership {
+	var owned []groupOwnership
+	for _, rg := range groups {
+		titles := make(map[string]bool)
+		for _, r := range rg.Releases {
+			for _, t := range releaseTracks(r, scc) {
+				if title, ok := trackTitle(t, scc); ok {
+					titles[title] = scc.TitleOwned[title]
+				}
+			}
+		}
+		o := groupOwnership{Title: rg.Title, ID: rg.ID}
+		for _, isOwned := range titles {
+			if isOwned {
+				o.Owned += 1
+			} else {
+				o.Missing += 1
+			}
+		}
+		owned = append(owned, o)
+	}
+	slices.SortFunc(owned, func(a, b groupOwnership) int {
+		if c := cmp.Compare(b.Missing, a.Missing); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Title, b.Title)
+	})
+	return owned
+}
+
+func printTitleGroups(groups []titleGroup) {
+	if len(groups) == 0 {
+		return
+	}
+	fmt.Println("\nEquivalent Titles")
+	fmt.Println(horizontal)
+	for _, g := range groups {
+		fmt.Printf("%v <- %v\n", g.Canonical, strings.Join(g.Titles, "; "))
+	}
+}
+
+func printOwnership(owned []groupOwnership) {
+	var complete int
+	fmt.Println("\nLibrary Ownership")
+	fmt.Println(horizontal)
+	fmt.Println("Owned | Missing | Release Group")
+	fmt.Println(horizontal)
+	for _, o := range owned {
+		fmt.Printf("%-7v %-9v %v\n", o.Owned, o.Missing, o.Title)
+		if o.Missing == 0 {
+			complete += 1
+		}
+	}
+	fmt.Println(complete, "of", len(owned), "release groups fully owned and excluded")
+}
+
+// How the lengths of two titles' recordings compare.
+type lengthComparison int
+
+const (
+	lengthsUncertain lengthComparison = iota
+	lengthsMatch
+	lengthsDiffer
+)
+
+// Returns the track's length, or its recording's when the track has none.
+func trackLength(t mb2.Track) time.Duration {
+	if t.Length.Duration > 0 {
+		return t.Length.Duration
+	}
+	return t.Recording.Length.Duration
+}
+
+// Compares two titles' track lengths. They match when their medians are within
+// the tolerance, as a title's many recordings often have some length close to
+// another title's by chance, and differ when even the closest are further apart
+// than the threshold. Either is disabled when zero, and titles without lengths
+// are uncertain.
+func compareLengths(a, b []time.Duration, tolerance, threshold time.Duration) lengthComparison {
+	if len(a) == 0 || len(b) == 0 {
+		return lengthsUncertain
+	}
+	if d := medianLength(a) - medianLength(b); tolerance > 0 && max(d, -d) <= tolerance {
+		return lengthsMatch
+	}
+	closest := time.Duration(math.MaxInt64)
+	for _, x := range a {
+		for _, y := range b {
+			d := x - y
+			if d < 0 {
+				d = -d
+			}
+			closest = min(closest, d)
 		}
-		return r
-	}, title)
+	}
+	if threshold > 0 && closest > threshold {
+		return lengthsDiffer
+	}
+	return lengthsUncertain
+}
+
+func medianLength(lengths []time.Duration) time.Duration {
+	sorted := slices.Clone(lengths)
+	slices.Sort(sorted)
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[mid-1] + sorted[mid]) / 2
+	}
+	return sorted[mid]
+}
+
+// Reduces the title to the canonical form in which it's compared.
+func CleanTitle(title string) string {
+	return titlematch.Normalize(title)
 }
 
 // Embeds title substitutions (whens tracks are the same but titled differently),
-// as well as tracks to ignore into the configuration.
+// as well as tracks to ignore and tracks already in the library into the configuration.
 func learnTracks(groups []mb2.ReleaseGroup, scc *setCoverConfig) {
-	subSets := make(map[string]map[string]bool)
+	equal := disjointset.New[string]()
 	ignore := make(map[string]bool)
 
-	// libraryIDs := make(map[mb2.MBID]bool)
+	owned := make(map[string]bool)
 	if scc.Remainder {
-		beetsTracks, beetErr := beets.ArtistTrackTitles(scc.ArtistMBID)
-		slog.Debug(
-			"current beets library",
-			"ArtistID", scc.ArtistMBID,
-			"Error", beetErr,
-			"Size", len(beetsTracks))
-		for _, t := range beetsTracks {
-			// libraryIDs[t.ID] = true
-			ignore[t.Title] = true
+		libraryIDs := make(map[mb2.MBID]bool)
+		for _, id := range scc.ArtistMBIDs {
+			ownedTracks, libErr := libraryTracks(*scc, id)
+			slog.Debug(
+				"current music library",
+				"Library", scc.Library,
+				"ArtistID", id,
+				"Error", libErr,
+				"Size", len(ownedTracks))
+			if libErr != nil {
+				fmt.Fprintf(messages, "Library tracks of %v could not be retrieved, so none count as owned: %v\n", id, libErr)
+			}
+			for _, t := range ownedTracks {
+				if t.ID != "" {
+					libraryIDs[t.ID] = true
+				}
+				owned[t.Title] = true
+			}
+		}
+		// Library tracks may be titled differently than on MusicBrainz.
+		for _, rg := range groups {
+			for _, r := range rg.Releases {
+				for _, t := range releaseTracks(r, *scc) {
+					if libraryIDs[t.ID] {
+						owned[t.Title] = true
+					}
+				}
+			}
 		}
 	}
 
 	titleSet := make(map[string]bool)
+	titleLengths := make(map[string][]time.Duration)
+	titleRecordings := make(map[string][]mb2.MBID)
+	stats := make(map[string]titleStats)
 	for _, rg := range groups {
 		for _, r := range rg.Releases {
-			for _, m := range r.Media {
-				for _, t := range m.Tracks {
-					titleSet[t.Title] = true
+			for _, t := range releaseTracks(r, *scc) {
+				titleSet[t.Title] = true
+				stats[t.Title] = stats[t.Title].add(r)
+				if t.Recording.ID != "" && !slices.Contains(titleRecordings[t.Title], t.Recording.ID) {
+					titleRecordings[t.Title] = append(titleRecordings[t.Title], t.Recording.ID)
+				}
+				if length := trackLength(t); length > 0 {
+					titleLengths[t.Title] = append(titleLengths[t.Title], length)
 				}
 			}
 		}
 	}
 
-	metric := metrics.NewLevenshtein()
-	metric.CaseSensitive = false
-	altTracks := make(map[string]bool)
+	metricName, threshold, err := titleMetric(scc.setCoverFlags)
+	if err != nil {
+		fmt.Fprintln(messages, err)
+		return
+	}
+	metric, _ := titlematch.NewMetric(metricName)
+	altTracks := make(map[string]musicinfo.AltKind)
+	terms := musicinfo.NewAltTerms(scc.AltTerms, scc.NotAltTerms)
 
 	// Process alternate tracks.
 	// Instead, check for existence of root in title set
 	for t := range titleSet {
 		var manual bool
-		if musicinfo.AlmostAltExp.MatchString(t) {
+		if musicinfo.AlmostAltExp.MatchString(t) && !terms.NotAltExp.MatchString(t) {
 			root := musicinfo.AlmostAltExp.ReplaceAllLiteralString(t, "")
-			if musicinfo.AltTrackExp.MatchString(t) && titleSet[root] {
-				altTracks[t] = true
+			kind := terms.Kind(t)
+			if kind != "" && titleSet[root] {
+				altTracks[t] = kind
 				manual = false
 			} else if prompt.BoolPrompt(fmt.Sprint("Is this an alternate track: ", t), true) {
-				altTracks[t] = true
+				if kind == "" {
+					kind = musicinfo.AltOther
+				}
+				altTracks[t] = kind
 				manual = true
 			}
 		}
-		if altTracks[t] && scc.DAlt {
+		if kind, ok := altTracks[t]; ok && slices.Contains(scc.DAlt, string(kind)) {
 			slog.Debug(
 				"track marked as an alternate",
 				"title", t,
+				"kind", kind,
 				"manual", manual)
 			ignore[t] = true
 			delete(titleSet, t)
 		}
 	}
 
+	// Titles whose recordings share a fingerprint are the same audio.
+	if lookup := acoustIDLookup(scc.setCoverFlags); lookup != nil {
+		fingerprinted, err := acoustIDGroups(lookup, titleSet, titleRecordings)
+		if err != nil {
+			fmt.Fprintln(messages, "AcoustIDs could not be retrieved:", err)
+		}
+		for _, group := range fingerprinted {
+			slog.Debug(
+				"titles sharing an AcoustID",
+				"titles", group)
+			for _, other := range group[1:] {
+				equal.Union(group[0], other)
+			}
+		}
+	}
+
+	titles := make([]string, 0, len(titleSet))
 	for t := range titleSet {
-		for other := range titleSet {
-			if t == other || (altTracks[t] != altTracks[other]) {
+		titles = append(titles, t)
+	}
+	slices.Sort(titles)
+	cleaned := make([]string, len(titles))
+	for i, t := range titles {
+		cleaned[i] = CleanTitle(t)
+	}
+	alike, err := titlematch.AlikePairs(cleaned, metricName, threshold)
+	if err != nil {
+		fmt.Fprintln(messages, err)
+		return
+	}
+	for _, p := range alike {
+		t, other := titles[p.I], titles[p.J]
+		if altTracks[t] != altTracks[other] || equal.Same(t, other) {
+			continue
+		}
+		if altTracks[t] != "" {
+			rootA := CleanTitle(terms.AltExp.ReplaceAllLiteralString(t, ""))
+			rootB := CleanTitle(terms.AltExp.ReplaceAllLiteralString(other, ""))
+			// Alternate versions of different songs share much of their titles.
+			if titlematch.Similarity(rootA, rootB, metric) <= threshold-0.1 {
 				continue
 			}
-			if strutil.Similarity(t, other, metric) > 0.6 {
-				if altTracks[t] && altTracks[other] {
-					rootA := musicinfo.AltTrackExp.ReplaceAllLiteralString(t, "")
-					rootB := musicinfo.AltTrackExp.ReplaceAllLiteralString(other, "")
-					if strutil.Similarity(rootA, rootB, metric) <= 0.5 {
-						continue
-					}
-				}
-				if CleanTitle(t) != CleanTitle(other) && !prompt.BoolPrompt(fmt.Sprintf(`Are tracks "%v" and "%v" equal?`, t, other), true) {
+		}
+		if cleaned[p.I] != cleaned[p.J] {
+			switch compareLengths(titleLengths[t], titleLengths[other], scc.LengthTolerance, scc.LengthThreshold) {
+			case lengthsDiffer:
+				slog.Debug(
+					"similar titles differ in length",
+					"a", t,
+					"b", other)
+				continue
+			case lengthsMatch:
+				slog.Debug(
+					"similar titles match in length",
+					"a", t,
+					"b", other)
+			default:
+				if !prompt.BoolPrompt(fmt.Sprintf(`Are tracks "%v" and "%v" equal?`, t, other), true) {
 					continue
 				}
-				m1, ok1 := subSets[t]
-				m2, ok2 := subSets[other]
-				if ok1 && ok2 {
-					// Merge sets
-					for k := range m2 {
-						m1[k] = true
-					}
-					subSets[other] = m1
-				} else if ok1 {
-					m1[other] = true
-					subSets[other] = m1
-				} else if ok2 {
-					m2[t] = true
-					subSets[t] = m2
-				} else {
-					m := map[string]bool{t: true, other: true}
-					subSets[t] = m
-					subSets[other] = m
-				}
 			}
 		}
-		delete(titleSet, t)
+		equal.Union(t, other)
 	}
 
 	sub := make(map[string]string)
-	for _, m := range subSets {
+	var titleGroups []titleGroup
+	for _, set := range equal.Groups() {
+		if len(set) < 2 {
+			continue
+		}
 		slog.Debug(
 			"titles determined to be equivalent",
-			"set", m)
-		set := make([]string, 0, len(m))
-		var ignored bool
-		for el := range m {
-			set = append(set, el)
-			if ignore[el] {
-				ignored = true
+			"set", set)
+		var isOwned bool
+		for _, el := range set {
+			if owned[el] {
+				isOwned = true
 			}
 		}
-		slices.SortFunc(set, func(a, b string) int {
-			return -1 * cmp.Compare(len(a), len(b))
-		})
-		if ignored {
-			ignore[set[0]] = true
+		slices.SortFunc(set, canonicalOrder(scc.Canonical, stats))
+		if isOwned {
+			owned[set[0]] = true
 		}
 		for _, el := range set {
 			sub[el] = set[0]
 		}
+		titleGroups = append(titleGroups, titleGroup{Canonical: set[0], Titles: set[1:]})
 	}
+	slices.SortFunc(titleGroups, func(a, b titleGroup) int {
+		return cmp.Compare(a.Canonical, b.Canonical)
+	})
 
 	scc.TitleIgnore = ignore
+	scc.TitleOwned = owned
 	scc.TitleSub = sub
+	scc.TitleGroups = titleGroups
 }
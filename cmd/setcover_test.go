@@ -3,13 +3,24 @@ package cmd
 import (
 	"fmt"
 	"math/rand"
+	"reflect"
 	"slices"
 	"strconv"
 	"testing"
+	"time"
 
 	mb2 "go.uploadedlobster.com/musicbrainzws2"
 )
 
+// Returns a release titled by its ID, with a medium of tracks of the titles.
+func testRelease(id mb2.MBID, titles ...string) mb2.Release {
+	var tracks []mb2.Track
+	for _, title := range titles {
+		tracks = append(tracks, mb2.Track{Title: title})
+	}
+	return mb2.Release{ID: id, Title: string(id), Media: []mb2.Medium{{Tracks: tracks}}}
+}
+
 func TestRemoveDuplicateReleases(t *testing.T) {
 	r := []mb2.Release{
 		{Media: []mb2.Medium{{Tracks: []mb2.Track{
@@ -55,6 +66,29 @@ func TestRemoveDuplicateReleases(t *testing.T) {
 	}
 }
 
+func TestCoverReleasesDeterministic(t *testing.T) {
+	date := func(s string) mb2.Date {
+		d, _ := parsePartialDate(s, false)
+		return mb2.Date{Time: d}
+	}
+	groups := []mb2.ReleaseGroup{{Releases: []mb2.Release{
+		{ID: "c", Date: date("2001"), Media: testRelease("", "a", "b").Media},
+		{ID: "undated", Media: testRelease("", "a", "b").Media},
+		{ID: "b", Date: date("1999"), Media: testRelease("", "a", "b").Media},
+		{ID: "a", Date: date("1999"), Media: testRelease("", "b", "a").Media},
+		{ID: "other", Media: testRelease("", "c").Media},
+	}}}
+	first := coverReleases(groups, setCoverConfig{})
+	for range 20 {
+		if res := coverReleases(groups, setCoverConfig{}); !reflect.DeepEqual(res, first) {
+			t.Fatalf(`coverReleases returned %v, then %v`, first, res)
+		}
+	}
+	if len(first) != 2 || first[0].ID != "a" || first[1].ID != "other" {
+		t.Errorf(`coverReleases = %v, wanted the earliest release "a" standing for its tracklist`, first)
+	}
+}
+
 func TestMinimalCombinations(t *testing.T) {
 	cases := []struct {
 		TrackMap map[string][]int
@@ -126,3 +160,187 @@ func BenchmarkCoverCombinations(b *testing.B) {
 		})
 	}
 }
+
+func TestOwnership(t *testing.T) {
+	groups := []mb2.ReleaseGroup{
+		{Title: "Partial", Releases: []mb2.Release{
+			{Title: "Partial", Media: []mb2.Medium{{Tracks: []mb2.Track{
+				{Title: "a"},
+				{Title: "b"},
+			}}}},
+			{Title: "Partial (Deluxe)", Media: []mb2.Medium{{Tracks: []mb2.Track{
+				{Title: "a"},
+				{Title: "b"},
+				{Title: "c (live)"},
+			}}}},
+		}},
+		{Title: "Owned", Releases: []mb2.Release{
+			{Title: "Owned", Media: []mb2.Medium{{Tracks: []mb2.Track{
+				{Title: "a"},
+				{Title: "d"},
+			}}}},
+		}},
+	}
+	scc := setCoverConfig{
+		TitleSub:   map[string]string{"c (live)": "c"},
+		TitleOwned: map[string]bool{"a": true, "d": true},
+	}
+	want := []groupOwnership{
+		{Title: "Partial", Owned: 1, Missing: 2},
+		{Title: "Owned", Owned: 2, Missing: 0},
+	}
+	res := ownership(groups, scc)
+	if !slices.Equal(res, want) {
+		t.Errorf(`ownership(%v) = %+v, wanted %+v`, groups, res, want)
+	}
+
+	var releases []mb2.Release
+	for _, rg := range groups {
+		releases = append(releases, rg.Releases...)
+	}
+	kept := dropFullyOwned(releases, scc)
+	if len(kept) != 2 || kept[0].Title != "Partial" || kept[1].Title != "Partial (Deluxe)" {
+		t.Errorf(`dropFullyOwned(%v) = %v, wanted the partially owned releases`, releases, kept)
+	}
+	if owned := releaseOwnedTitles(releases[1], scc); !slices.Equal(owned, []string{"a"}) {
+		t.Errorf(`releaseOwnedTitles(%v) = %v, wanted [a]`, releases[1], owned)
+	}
+	if tracks := releaseTrackTitles(releases[1], scc); !slices.Equal(tracks, []string{"b", "c"}) {
+		t.Errorf(`releaseTrackTitles(%v) = %v, wanted [b c]`, releases[1], tracks)
+	}
+}
+
+func TestReleaseTracksAppearances(t *testing.T) {
+	own := mb2.Release{ID: "own", Media: []mb2.Medium{{Tracks: []mb2.Track{
+		{Title: "a", Recording: mb2.Recording{ID: "rec-a"}},
+		{Title: "guest", Recording: mb2.Recording{ID: "rec-guest"}},
+	}}}}
+	compilation := mb2.Release{ID: "compilation", Media: []mb2.Medium{{Tracks: []mb2.Track{
+		{Title: "b", Recording: mb2.Recording{ID: "rec-b"}},
+		{Title: "other", Recording: mb2.Recording{ID: "rec-other"}},
+	}}}}
+	scc := setCoverConfig{
+		AppearanceReleases: map[mb2.MBID]bool{"compilation": true},
+		ArtistRecordings:   map[mb2.MBID]bool{"rec-a": true, "rec-b": true},
+	}
+	if res := releaseTrackTitles(own, scc); !slices.Equal(res, []string{"a", "guest"}) {
+		t.Errorf(`releaseTrackTitles(%v) = %v, wanted every track of the artist's own release`, own, res)
+	}
+	if res := releaseTrackTitles(compilation, scc); !slices.Equal(res, []string{"b"}) {
+		t.Errorf(`releaseTrackTitles(%v) = %v, wanted only the artist's recordings`, compilation, res)
+	}
+}
+
+func TestReleaseTracksCredits(t *testing.T) {
+	artist := mb2.ArtistCredit{{Artist: mb2.Artist{ID: "artist"}}}
+	guest := mb2.ArtistCredit{{Artist: mb2.Artist{ID: "guest"}}}
+	featured := mb2.ArtistCredit{{Artist: mb2.Artist{ID: "guest"}, JoinPhrase: " feat. "}, {Artist: mb2.Artist{ID: "artist"}}}
+	release := mb2.Release{ArtistCredit: artist, Media: []mb2.Medium{{Tracks: []mb2.Track{
+		{Title: "a"},
+		{Title: "b", ArtistCredit: guest},
+		{Title: "c", ArtistCredit: featured},
+		{Title: "d", Recording: mb2.Recording{ArtistCredit: guest}},
+	}}}}
+	cases := []struct {
+		Flags setCoverFlags
+		Want  []string
+	}{
+		{setCoverFlags{}, []string{"a", "b", "c", "d"}},
+		{setCoverFlags{CreditedOnly: true}, []string{"a", "c"}},
+		{setCoverFlags{ExcludeFeatured: true}, []string{"a", "b", "d"}},
+		{setCoverFlags{CreditedOnly: true, ExcludeFeatured: true}, []string{"a"}},
+	}
+	for _, c := range cases {
+		scc := setCoverConfig{setCoverFlags: c.Flags, ArtistMBIDs: []mb2.MBID{"artist"}}
+		if res := releaseTrackTitles(release, scc); !slices.Equal(res, c.Want) {
+			t.Errorf(`releaseTrackTitles with %+v = %v, wanted %v`, c.Flags, res, c.Want)
+		}
+	}
+}
+
+func TestAltKinds(t *testing.T) {
+	all := []string{"live", "remix", "acoustic", "instrumental", "demo", "radio-edit", "extended", "remaster", "other"}
+	cases := []struct {
+		In    []string
+		Want  []string
+		Error bool
+	}{
+		{nil, nil, false},
+		{[]string{"false"}, nil, false},
+		{[]string{"all"}, all, false},
+		{[]string{"true"}, all, false},
+		{[]string{"Remix", " live", "remix"}, []string{"remix", "live"}, false},
+		{[]string{"remix", "all"}, all, false},
+		{[]string{"cover"}, nil, true},
+	}
+	for _, c := range cases {
+		res, err := altKinds(c.In)
+		if (err != nil) != c.Error {
+			t.Errorf(`altKinds(%v) returned error %v`, c.In, err)
+			continue
+		}
+		if !slices.Equal(res, c.Want) {
+			t.Errorf(`altKinds(%v) = %v, wanted %v`, c.In, res, c.Want)
+		}
+	}
+}
+
+func TestCompareLengths(t *testing.T) {
+	s := func(secs ...int) []time.Duration {
+		var lengths []time.Duration
+		for _, sec := range secs {
+			lengths = append(lengths, time.Duration(sec)*time.Second)
+		}
+		return lengths
+	}
+	cases := []struct {
+		A, B                 []time.Duration
+		Tolerance, Threshold time.Duration
+		Want                 lengthComparison
+	}{
+		{s(), s(200), 3 * time.Second, 20 * time.Second, lengthsUncertain},
+		{s(200), s(202), 3 * time.Second, 20 * time.Second, lengthsMatch},
+		{s(200), s(203), 3 * time.Second, 20 * time.Second, lengthsMatch},
+		{s(200), s(210), 3 * time.Second, 20 * time.Second, lengthsUncertain},
+		{s(200), s(240), 3 * time.Second, 20 * time.Second, lengthsDiffer},
+		{s(200, 200, 201), s(202, 202, 320), 3 * time.Second, 20 * time.Second, lengthsMatch},
+		// Distinct songs, such as "Part II" and "Part III", sharing one nearby length.
+		{s(200, 320), s(240, 318), 3 * time.Second, 20 * time.Second, lengthsUncertain},
+		{s(250, 251, 252), s(253, 300, 301), 3 * time.Second, 20 * time.Second, lengthsUncertain},
+		{s(200), s(202), 0, 20 * time.Second, lengthsUncertain},
+		{s(200), s(240), 3 * time.Second, 0, lengthsUncertain},
+	}
+	for _, c := range cases {
+		if res := compareLengths(c.A, c.B, c.Tolerance, c.Threshold); res != c.Want {
+			t.Errorf(`compareLengths(%v, %v, %v, %v) = %v, wanted %v`, c.A, c.B, c.Tolerance, c.Threshold, res, c.Want)
+		}
+	}
+}
+
+func TestReleaseTracksMedleys(t *testing.T) {
+	release := mb2.Release{Media: []mb2.Medium{{Tracks: []mb2.Track{
+		{ID: "1", Title: "A"},
+		{ID: "2", Title: "Medley: B - C"},
+		{ID: "3", Title: "D / E", Recording: mb2.Recording{ID: "rec"}},
+	}}}}
+	cases := []struct {
+		Medleys string
+		Want    []string
+	}{
+		{"", []string{"A", "D / E", "Medley: B - C"}},
+		{medleysItem, []string{"A", "D / E", "Medley: B - C"}},
+		{medleysCover, []string{"A", "B", "C", "D", "E"}},
+		{medleysSkip, []string{"A"}},
+	}
+	for _, c := range cases {
+		scc := setCoverConfig{setCoverFlags: setCoverFlags{Medleys: c.Medleys}}
+		if res := releaseTrackTitles(release, scc); !slices.Equal(res, c.Want) {
+			t.Errorf(`releaseTrackTitles with medleys %q = %v, wanted %v`, c.Medleys, res, c.Want)
+		}
+	}
+	for _, track := range releaseTracks(release, setCoverConfig{setCoverFlags: setCoverFlags{Medleys: medleysCover}}) {
+		if track.Title == "D" && (track.ID != "3" || track.Recording.ID != "") {
+			t.Errorf(`medley song %+v kept the wrong IDs, wanted the medley's track and no recording`, track)
+		}
+	}
+}
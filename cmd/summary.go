@@ -0,0 +1,200 @@
+package cmd
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/frigorific44/musicgreed/disjointset"
+	mb2 "go.uploadedlobster.com/musicbrainzws2"
+)
+
+// The tied minimal covers in brief: the releases every cover shares, and the
+// slots the covers fill from interchangeable releases.
+type coverSummary struct {
+	Covers int          `json:"covers"`
+	Size   int          `json:"size"`
+	Common []releaseRef `json:"common"`
+	Slots  []coverSlot  `json:"slots"`
+	// Whether every combination of picks from the slots is a cover.
+	Independent bool `json:"independent"`
+}
+
+// Interchangeable releases, of which each cover picks the same number.
+type coverSlot struct {
+	Pick     int          `json:"pick"`
+	Releases []releaseRef `json:"releases"`
+}
+
+// Summarizes the covers. Releases two covers swap one for the other share a
+// slot, as do all releases picked in varying numbers across covers.
+func summarizeCovers(covers [][]mb2.Release) coverSummary {
+	summary := coverSummary{Covers: len(covers), Common: []releaseRef{}, Slots: []coverSlot{}}
+	if len(covers) == 0 {
+		return summary
+	}
+	summary.Size = len(covers[0])
+
+	titles := make(map[mb2.MBID]string)
+	counts := make(map[mb2.MBID]int)
+	sets := make([]map[mb2.MBID]bool, len(covers))
+	for i, cover := range covers {
+		sets[i] = make(map[mb2.MBID]bool, len(cover))
+		for _, r := range cover {
+			titles[r.ID] = r.Title
+			counts[r.ID] += 1
+			sets[i][r.ID] = true
+		}
+	}
+
+	slots := disjointset.New[mb2.MBID]()
+	for id, n := range counts {
+		if n == len(covers) {
+			summary.Common = append(summary.Common, releaseRef{Title: titles[id], ID: id})
+		} else {
+			slots.Add(id)
+		}
+	}
+	for i := range sets {
+		for j := i + 1; j < len(sets); j++ {
+			if x, y, ok := swapped(sets[i], sets[j]); ok {
+				slots.Union(x, y)
+			}
+		}
+	}
+	var varying []mb2.MBID
+	for _, group := range slots.Groups() {
+		pick, constant := groupPick(group, sets)
+		if !constant {
+			varying = append(varying, group...)
+			continue
+		}
+		summary.Slots = append(summary.Slots, newCoverSlot(pick, group, titles))
+	}
+	if len(varying) > 0 {
+		pick, _ := groupPick(varying, sets)
+		summary.Slots = append(summary.Slots, newCoverSlot(pick, varying, titles))
+	}
+
+	slices.SortFunc(summary.Common, compareReleaseRefs)
+	slices.SortFunc(summary.Slots, func(a, b coverSlot) int {
+		return compareReleaseRefs(a.Releases[0], b.Releases[0])
+	})
+	combinations := 1
+	for _, s := range summary.Slots {
+		combinations *= binomial(len(s.Releases), s.Pick)
+	}
+	summary.Independent = combinations == len(covers)
+	return summary
+}
+
+// Returns the releases two covers swap, when they differ by exactly one.
+func swapped(a, b map[mb2.MBID]bool) (mb2.MBID, mb2.MBID, bool) {
+	var onlyA, onlyB []mb2.MBID
+	for id := range a {
+		if !b[id] {
+			onlyA = append(onlyA, id)
+		}
+	}
+	for id := range b {
+		if !a[id] {
+			onlyB = append(onlyB, id)
+		}
+	}
+	if len(onlyA) != 1 || len(onlyB) != 1 {
+		return "", "", false
+	}
+	return onlyA[0], onlyB[0], true
+}
+
+// Returns how many of the group's releases the first cover picks, and whether
+// every cover picks as many.
+func groupPick(group []mb2.MBID, sets []map[mb2.MBID]bool) (int, bool) {
+	pick := -1
+	for _, set := range sets {
+		var n int
+		for _, id := range group {
+			if set[id] {
+				n += 1
+			}
+		}
+		if pick >= 0 && n != pick {
+			return pick, false
+		}
+		pick = n
+	}
+	return pick, true
+}
+
+func newCoverSlot(pick int, group []mb2.MBID, titles map[mb2.MBID]string) coverSlot {
+	slot := coverSlot{Pick: pick}
+	for _, id := range group {
+		slot.Releases = append(slot.Releases, releaseRef{Title: titles[id], ID: id})
+	}
+	slices.SortFunc(slot.Releases, compareReleaseRefs)
+	return slot
+}
+
+func compareReleaseRefs(a, b releaseRef) int {
+	return cmp.Or(cmp.Compare(a.Title, b.Title), cmp.Compare(a.ID, b.ID))
+}
+
+func binomial(n, k int) int {
+	result := 1
+	for i := 1; i <= k; i++ {
+		result = result * (n - k + i) / i
+	}
+	return result
+}
+
+func printCoverSummary(summary coverSummary) {
+	fmt.Print("\n> Set Cover Summary, ", summary.Covers, " covers of ")
+	fmt.Println(summary.Size, "releases")
+	fmt.Println(horizontal)
+	if len(summary.Common) > 0 {
+		var titles []string
+		for _, r := range summary.Common {
+			titles = append(titles, r.Title)
+		}
+		fmt.Println("Common to every cover:")
+		fmt.Println(strings.Join(titles, "; "))
+	}
+	for _, s := range summary.Slots {
+		var titles []string
+		for _, r := range s.Releases {
+			titles = append(titles, r.Title)
+		}
+		if s.Pick == 1 {
+			fmt.Printf("\nPick one of {%v}\n", strings.Join(titles, ", "))
+		} else {
+			fmt.Printf("\nPick %v of {%v}\n", s.Pick, strings.Join(titles, ", "))
+		}
+	}
+	if !summary.Independent {
+		fmt.Println("\nNot every combination of picks is a cover; use --all-covers to list them.")
+	}
+}
+
+// Prints the tracks already owned on each release of the covers.
+func printSummaryOwned(covers [][]mb2.Release, scc setCoverConfig) {
+	fmt.Println("\nAlready Owned:")
+	seen := make(map[mb2.MBID]bool)
+	var releases []mb2.Release
+	for _, cover := range covers {
+		for _, r := range cover {
+			if !seen[r.ID] {
+				seen[r.ID] = true
+				releases = append(releases, r)
+			}
+		}
+	}
+	slices.SortFunc(releases, func(a, b mb2.Release) int {
+		return cmp.Or(cmp.Compare(a.Title, b.Title), cmp.Compare(a.ID, b.ID))
+	})
+	for _, r := range releases {
+		if owned := releaseOwnedTitles(r, scc); len(owned) > 0 {
+			fmt.Printf("%v: %v\n", r.Title, strings.Join(owned, "; "))
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"slices"
+	"testing"
+
+	mb2 "go.uploadedlobster.com/musicbrainzws2"
+)
+
+func TestSummarizeCovers(t *testing.T) {
+	cover := func(ids ...mb2.MBID) []mb2.Release {
+		var releases []mb2.Release
+		for _, id := range ids {
+			releases = append(releases, testRelease(id))
+		}
+		return releases
+	}
+	ids := func(refs []releaseRef) []mb2.MBID {
+		var result []mb2.MBID
+		for _, r := range refs {
+			result = append(result, r.ID)
+		}
+		return result
+	}
+	type slot struct {
+		Pick     int
+		Releases []mb2.MBID
+	}
+	cases := []struct {
+		Covers      [][]mb2.Release
+		Common      []mb2.MBID
+		Slots       []slot
+		Independent bool
+	}{
+		{[][]mb2.Release{cover("a", "b")}, []mb2.MBID{"a", "b"}, nil, true},
+		{
+			[][]mb2.Release{cover("a", "b", "d"), cover("a", "c", "d")},
+			[]mb2.MBID{"a", "d"},
+			[]slot{{1, []mb2.MBID{"b", "c"}}},
+			true,
+		},
+		{
+			[][]mb2.Release{cover("a", "b", "d", "e"), cover("a", "c", "d", "e"), cover("a", "b", "d", "f"), cover("a", "c", "d", "f")},
+			[]mb2.MBID{"a", "d"},
+			[]slot{{1, []mb2.MBID{"b", "c"}}, {1, []mb2.MBID{"e", "f"}}},
+			true,
+		},
+		{
+			[][]mb2.Release{cover("a", "b"), cover("a", "c"), cover("d", "e")},
+			nil,
+			[]slot{{2, []mb2.MBID{"a", "b", "c", "d", "e"}}},
+			false,
+		},
+	}
+	for _, c := range cases {
+		res := summarizeCovers(c.Covers)
+		var slots []slot
+		for _, s := range res.Slots {
+			slots = append(slots, slot{s.Pick, ids(s.Releases)})
+		}
+		if !slices.Equal(ids(res.Common), c.Common) || res.Independent != c.Independent ||
+			!slices.EqualFunc(slots, c.Slots, func(a, b slot) bool {
+				return a.Pick == b.Pick && slices.Equal(a.Releases, b.Releases)
+			}) {
+			t.Errorf(`summarizeCovers(%v) = %+v, wanted common %v, slots %v, independent %v`,
+				c.Covers, res, c.Common, c.Slots, c.Independent)
+		}
+	}
+}
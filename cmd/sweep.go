@@ -0,0 +1,238 @@
+package cmd
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/frigorific44/musicgreed/beets"
+	"github.com/frigorific44/musicgreed/musicinfo"
+	"github.com/spf13/cobra"
+	mb2 "go.uploadedlobster.com/musicbrainzws2"
+)
+
+const (
+	variousArtistsMBID mb2.MBID = "89ad4ac3-39f7-470e-963a-56509c546377"
+	sweepTableHeader   string   = "Missing | Releases | Artist"
+)
+
+var (
+	// Sweep progress saved by a version with differently typed flags.
+	errStaleSweepState = errors.New("sweep progress is from another version")
+)
+
+// sweepCmd represents the sweep command
+func NewSweepCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   `sweep`,
+		Short: "Compute the remainder set cover for every artist in a beets library.",
+		Long: "This command enumerates every artist in your beets library with a " +
+			"MusicBrainz ID, computes the set cover on the tracks missing for each, and " +
+			"ranks the artists by the number of missing songs and the releases needed to " +
+			"collect them. Progress is saved after each artist, so an interrupted sweep " +
+			"resumes where it left off when run again with the same flags." +
+			"\n\n`musicgreed sweep --dalt`" +
+			"\n\nTo discard saved progress and sweep the whole library again:" +
+			"\n\n`musicgreed sweep --restart`",
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			flags, err := packageSetCoverFlags(cmd)
+			if err != nil {
+				fmt.Println(err)
+				return
+			}
+			format, err := outputFormat(cmd)
+			if err != nil {
+				fmt.Println(err)
+				return
+			}
+			flags.Remainder = true
+			flags.Library = libraryBeets
+			statePath, _ := cmd.Flags().GetString("state")
+			restart, _ := cmd.Flags().GetBool("restart")
+			if statePath == "" {
+				if statePath, err = defaultSweepStatePath(); err != nil {
+					fmt.Fprintln(messages, err)
+					return
+				}
+			}
+
+			state := sweepState{Flags: flags, Results: make(map[mb2.MBID]sweepResult)}
+			if !restart {
+				saved, err := loadSweepState(statePath)
+				if errors.Is(err, errStaleSweepState) {
+					fmt.Fprintln(messages, "Starting the sweep over, as", err)
+				} else if err != nil {
+					fmt.Fprintln(messages, err)
+					return
+				}
+				if len(saved.Results) > 0 && sameSweepFlags(saved.Flags, flags) {
+					state = saved
+					fmt.Fprintln(messages, "Resuming sweep,", len(state.Results), "artists already done")
+				}
+			}
+
+			library, err := openLibrary(flags)
+			if err != nil {
+				fmt.Fprintln(messages, err)
+				return
+			}
+			artists, err := beets.LibraryArtists()
+			if err != nil {
+				fmt.Fprintln(messages, err)
+				return
+			}
+			client, stop := musicinfo.NewMGClient()
+			defer stop()
+
+			for i, artist := range artists {
+				if _, done := state.Results[artist.ID]; done || artist.ID == variousArtistsMBID {
+					continue
+				}
+				fmt.Fprintf(messages, "[%v/%v] Retrieving music for %v...\n", i+1, len(artists), artist.Name)
+				scc := setCoverConfig{setCoverFlags: flags, ArtistMBIDs: []mb2.MBID{artist.ID}, MusicLibrary: library}
+				groups, err := artistReleaseGroups(client, &scc)
+				if err != nil {
+					fmt.Fprintln(messages, err)
+					continue
+				}
+				state.Results[artist.ID] = sweepArtist(artist, groups, scc)
+				if err := saveSweepState(statePath, state); err != nil {
+					fmt.Fprintln(messages, err)
+					return
+				}
+			}
+
+			ranked := rankSweep(state.Results)
+			if format == formatJSON {
+				if err := writeJSON(ranked); err != nil {
+					fmt.Fprintln(messages, err)
+				}
+				return
+			}
+			printSweep(ranked)
+		},
+	}
+
+	addFilterFlags(cmd)
+	addMatchFlags(cmd)
+	cmd.Flags().String("state", "", "path to the sweep progress file (default in the user cache directory)")
+	cmd.Flags().Bool("restart", false, "discard saved progress and sweep every artist again")
+	addOutputFlags(cmd)
+
+	return cmd
+}
+
+type sweepResult struct {
+	ArtistMBID mb2.MBID
+	Name       string
+	Missing    int
+	Releases   int
+	Covers     int
+}
+
+type sweepState struct {
+	Flags   setCoverFlags
+	Results map[mb2.MBID]sweepResult
+}
+
+// Computes the remainder set cover for an artist.
+func sweepArtist(artist mb2.Artist, groups []mb2.ReleaseGroup, scc setCoverConfig) sweepResult {
+	result := sweepResult{ArtistMBID: artist.ID, Name: artist.Name}
+	releases := coverReleases(groups, scc)
+	missing := make(map[string]bool)
+	for _, r := range releases {
+		for _, t := range releaseTrackTitles(r, scc) {
+			missing[t] = true
+		}
+	}
+	result.Missing = len(missing)
+	if len(missing) > 0 {
+		covers := setcovers(releases, scc)
+		result.Covers = len(covers)
+		if len(covers) > 0 {
+			result.Releases = len(covers[0])
+		}
+	}
+	return result
+}
+
+// Ranks artists by the most missing songs, then by the fewest releases needed.
+func rankSweep(results map[mb2.MBID]sweepResult) []sweepResult {
+	ranked := make([]sweepResult, 0, len(results))
+	for _, r := range results {
+		ranked = append(ranked, r)
+	}
+	slices.SortFunc(ranked, func(a, b sweepResult) int {
+		if c := cmp.Compare(b.Missing, a.Missing); c != 0 {
+			return c
+		}
+		if c := cmp.Compare(a.Releases, b.Releases); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Name, b.Name)
+	})
+	return ranked
+}
+
+func printSweep(ranked []sweepResult) {
+	sweepHorizontal := horizontal + horizontal
+	fmt.Println()
+	fmt.Println(sweepHorizontal)
+	fmt.Println(sweepTableHeader)
+	fmt.Println(sweepHorizontal)
+	for _, r := range ranked {
+		fmt.Printf("%-9v %-10v %v\n", r.Missing, r.Releases, r.Name)
+	}
+}
+
+// Compares flags as they're saved, without the keys left out of the state.
+func sameSweepFlags(saved, flags setCoverFlags) bool {
+	a, errA := json.Marshal(saved)
+	b, errB := json.Marshal(flags)
+	return errA == nil && errB == nil && bytes.Equal(a, b)
+}
+
+func defaultSweepStatePath() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf(`user cache directory not found: %w`, err)
+	}
+	return filepath.Join(dir, "musicgreed", "sweep.json"), nil
+}
+
+func loadSweepState(path string) (sweepState, error) {
+	var state sweepState
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return state, nil
+	} else if err != nil {
+		return state, fmt.Errorf(`reading sweep progress "%v": %w`, path, err)
+	}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return sweepState{}, fmt.Errorf(`%w and did not unmarshal cleanly: %w`, errStaleSweepState, err)
+	}
+	return state, nil
+}
+
+// Writes the state to a temporary file first, so an interruption never leaves it half written.
+func saveSweepState(path string, state sweepState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf(`marshaling sweep progress: %w`, err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf(`creating sweep progress directory: %w`, err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf(`writing sweep progress "%v": %w`, tmp, err)
+	}
+	return os.Rename(tmp, path)
+}
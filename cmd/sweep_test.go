@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	mb2 "go.uploadedlobster.com/musicbrainzws2"
+)
+
+func TestRankSweep(t *testing.T) {
+	results := map[mb2.MBID]sweepResult{
+		"a": {Name: "A", Missing: 3, Releases: 2},
+		"b": {Name: "B", Missing: 10, Releases: 4},
+		"c": {Name: "C", Missing: 3, Releases: 1},
+		"d": {Name: "D", Missing: 0},
+	}
+	var names []string
+	for _, r := range rankSweep(results) {
+		names = append(names, r.Name)
+	}
+	if want := []string{"B", "C", "A", "D"}; !reflect.DeepEqual(names, want) {
+		t.Errorf(`rankSweep(%v) ranked %v, wanted %v`, results, names, want)
+	}
+}
+
+func TestSweepStateRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", "sweep.json")
+	empty, err := loadSweepState(path)
+	if err != nil || len(empty.Results) != 0 {
+		t.Fatalf(`loadSweepState on a missing file = %+v, %v, wanted empty state`, empty, err)
+	}
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	cmd := NewSweepCmd()
+	if err := cmd.ParseFlags([]string{"--dsec=live", "--acoustid-key=secret"}); err != nil {
+		t.Fatal(err)
+	}
+	flags, err := packageSetCoverFlags(cmd)
+	if err != nil {
+		t.Fatalf(`packageSetCoverFlags returned error: %v`, err)
+	}
+	flags.Remainder, flags.Library = true, libraryBeets
+	state := sweepState{
+		Flags:   flags,
+		Results: map[mb2.MBID]sweepResult{"a": {ArtistMBID: "a", Name: "A", Missing: 3, Releases: 2, Covers: 1}},
+	}
+	if err := saveSweepState(path, state); err != nil {
+		t.Fatalf(`saveSweepState returned error: %v`, err)
+	}
+	loaded, err := loadSweepState(path)
+	if err != nil {
+		t.Fatalf(`loadSweepState returned error: %v`, err)
+	}
+	// The AcoustID key isn't saved, but the sweep still resumes.
+	if !reflect.DeepEqual(loaded.Results, state.Results) || !sameSweepFlags(loaded.Flags, state.Flags) {
+		t.Errorf(`loadSweepState = %+v, wanted to resume %+v`, loaded, state)
+	}
+
+	// Progress saved while --dalt was a boolean.
+	if err := os.WriteFile(path, []byte(`{"Flags":{"DAlt":true},"Results":{}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if stale, err := loadSweepState(path); !errors.Is(err, errStaleSweepState) || len(stale.Results) != 0 {
+		t.Errorf(`loadSweepState on older progress = %+v, %v, wanted empty state and a stale error`, stale, err)
+	}
+}
@@ -0,0 +1,491 @@
+package cmd
+
+import (
+	"cmp"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/frigorific44/musicgreed/config"
+	"github.com/frigorific44/musicgreed/musicinfo"
+	"github.com/spf13/cobra"
+	mb2 "go.uploadedlobster.com/musicbrainzws2"
+)
+
+const (
+	formatRSS string = "rss"
+	// The most items kept in an RSS digest file, newest first.
+	rssMaxItems int = 100
+)
+
+// watchCmd represents the watch command
+func NewWatchCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   `watch`,
+		Short: "Check watched artists for new release groups and songs.",
+		Long: "This command checks every artist on the watchlist for release groups and " +
+			"songs added to MusicBrainz since the last check, and prints a digest of them. " +
+			"The first check of an artist only takes a snapshot to compare later checks " +
+			"against. Artists are added to and removed from the watchlist by name or MBID:" +
+			"\n\n`musicgreed watch add artist`" +
+			"\n\n`musicgreed watch remove artist`" +
+			"\n\n`musicgreed watch list`" +
+			"\n\nThe watchlist is kept beside the configuration file, and the snapshots in " +
+			"the musicgreed directory of the user cache directory. Nothing is asked while " +
+			"checking, and progress goes to standard error, so the check may run from cron. " +
+			"The digest may be text, JSON, or an RSS feed, and may be written to a file, where " +
+			"new RSS items are added to those already there:" +
+			"\n\n`musicgreed watch --format=rss --digest=musicgreed.xml`",
+		Args:        cobra.NoArgs,
+		Annotations: map[string]string{annotationStderr: ""},
+		Run: func(cmd *cobra.Command, args []string) {
+			format, _ := cmd.Flags().GetString("format")
+			if !slices.Contains([]string{formatText, formatJSON, formatRSS}, format) {
+				fmt.Fprintf(messages, "unknown digest format %q, expected %v, %v, or %v\n", format, formatText, formatJSON, formatRSS)
+				return
+			}
+			digestPath, _ := cmd.Flags().GetString("digest")
+			watchlist, _, err := loadWatchlist(cmd)
+			if err != nil {
+				fmt.Fprintln(messages, err)
+				return
+			}
+			if len(watchlist.Artists) == 0 {
+				fmt.Fprintln(messages, "No artists are watched; add them with `musicgreed watch add artist`")
+				return
+			}
+			statePath, _ := cmd.Flags().GetString("state")
+			if statePath == "" {
+				if statePath, err = defaultWatchStatePath(); err != nil {
+					fmt.Fprintln(messages, err)
+					return
+				}
+			}
+			snapshots, err := loadWatchSnapshots(statePath)
+			if err != nil {
+				fmt.Fprintln(messages, err)
+				return
+			}
+			terms := musicinfo.NewAltTerms(configAltTerms, configNotAltTerms)
+
+			client, stop := musicinfo.NewMGClient()
+			defer stop()
+			digest := watchDigest{Checked: time.Now().UTC().Truncate(time.Second), Artists: []artistDigest{}}
+			for i, artist := range watchlist.Artists {
+				id := mb2.MBID(artist.ID)
+				fmt.Fprintf(messages, "[%v/%v] Checking %v...\n", i+1, len(watchlist.Artists), cmp.Or(artist.Name, artist.ID))
+				groups, err := musicinfo.ReleaseGroupsByArtist(client, id, "", musicinfo.ReleaseIncludes(false))
+				if err != nil {
+					fmt.Fprintln(messages, err)
+					continue
+				}
+				previous, ok := snapshots[id]
+				current := takeWatchSnapshot(id, groups, terms, digest.Checked)
+				current.Name = cmp.Or(current.Name, artist.Name, artist.ID)
+				if ok {
+					if changes := diffWatchSnapshots(id, previous, current); len(changes.ReleaseGroups) > 0 || len(changes.Songs) > 0 {
+						digest.Artists = append(digest.Artists, changes)
+					}
+					current.keep(previous)
+				} else {
+					fmt.Fprintf(messages, "Now watching %v, with %v release groups and %v songs\n", current.Name, len(current.Groups), len(current.Songs))
+				}
+				snapshots[id] = current
+			}
+
+			// The digest is written before the snapshots, so a failure to write it
+			// leaves the changes to be reported by the next check.
+			if err := writeWatchDigest(digestPath, format, digest); err != nil {
+				fmt.Fprintln(messages, err)
+				return
+			}
+			if err := saveWatchSnapshots(statePath, snapshots); err != nil {
+				fmt.Fprintln(messages, err)
+			}
+		},
+	}
+
+	cmd.PersistentFlags().String("watchlist", "", "path to the watchlist (default beside the configuration file)")
+	cmd.Flags().String("state", "", "path to the snapshots of watched artists (default in the user cache directory)")
+	cmd.Flags().String("format", formatText, "digest format (text, json, rss)")
+	cmd.Flags().String("digest", "", "path to write the digest to, rather than standard output")
+
+	cmd.AddCommand(
+		NewWatchAddCmd(),
+		NewWatchRemoveCmd(),
+		NewWatchListCmd(),
+	)
+
+	return cmd
+}
+
+func NewWatchAddCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   `add artist...`,
+		Short: "Add artists to the watchlist, by name or MBID.",
+		Args:  cobra.MinimumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			watchlist, path, err := loadWatchlist(cmd)
+			if err != nil {
+				fmt.Println(err)
+				return
+			}
+			client, stop := musicinfo.NewMGClient()
+			defer stop()
+			for _, arg := range args {
+				mbid, err := artistMBID(client, arg)
+				if err != nil {
+					fmt.Printf("Artist ID could not be retrieved for %q\n", arg)
+					continue
+				}
+				artist := config.WatchedArtist{ID: string(mbid)}
+				if arg != artist.ID {
+					artist.Name = arg
+				}
+				if watchlist.Add(artist) {
+					fmt.Println("Watching", cmp.Or(artist.Name, artist.ID))
+				} else {
+					fmt.Println("Already watching", cmp.Or(artist.Name, artist.ID))
+				}
+			}
+			if err := watchlist.Save(path); err != nil {
+				fmt.Println(err)
+			}
+		},
+	}
+}
+
+func NewWatchRemoveCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   `remove artist...`,
+		Short: "Remove artists from the watchlist, by name or MBID.",
+		Args:  cobra.MinimumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			watchlist, path, err := loadWatchlist(cmd)
+			if err != nil {
+				fmt.Println(err)
+				return
+			}
+			for _, arg := range args {
+				if !watchlist.Remove(arg) {
+					fmt.Printf("%q is not on the watchlist\n", arg)
+				}
+			}
+			if err := watchlist.Save(path); err != nil {
+				fmt.Println(err)
+			}
+		},
+	}
+}
+
+func NewWatchListCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   `list`,
+		Short: "List the artists on the watchlist.",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			watchlist, _, err := loadWatchlist(cmd)
+			if err != nil {
+				fmt.Println(err)
+				return
+			}
+			for _, a := range watchlist.Artists {
+				fmt.Printf("%-36v %v\n", a.ID, a.Name)
+			}
+		},
+	}
+}
+
+// Loads the watchlist from the path given by flag or by default, returning the path.
+func loadWatchlist(cmd *cobra.Command) (config.Watchlist, string, error) {
+	path, _ := cmd.Flags().GetString("watchlist")
+	if path == "" {
+		var err error
+		if path, err = config.DefaultWatchlistPath(); err != nil {
+			return config.Watchlist{}, path, err
+		}
+	}
+	watchlist, err := config.LoadWatchlist(path)
+	return watchlist, path, err
+}
+
+// The release groups and songs of an artist as of a check.
+type watchSnapshot struct {
+	Name    string
+	Checked time.Time
+	// Release group titles by MBID.
+	Groups map[mb2.MBID]string
+	// Song titles by their cleaned titles, without alternate version suffixes.
+	Songs map[string]string
+}
+
+func takeWatchSnapshot(artistID mb2.MBID, groups []mb2.ReleaseGroup, terms musicinfo.AltTerms, checked time.Time) watchSnapshot {
+	snapshot := watchSnapshot{Checked: checked, Groups: make(map[mb2.MBID]string), Songs: make(map[string]string)}
+	for _, rg := range groups {
+		snapshot.Groups[rg.ID] = rg.Title
+		for _, credit := range rg.ArtistCredit {
+			if credit.Artist.ID == artistID && snapshot.Name == "" {
+				snapshot.Name = credit.Artist.Name
+			}
+		}
+		for _, r := range rg.Releases {
+			for _, m := range r.Media {
+				for _, t := range m.Tracks {
+					title := strings.TrimSpace(terms.AltExp.ReplaceAllLiteralString(t.Title, ""))
+					song := CleanTitle(title)
+					if _, ok := snapshot.Songs[song]; !ok && song != "" {
+						snapshot.Songs[song] = title
+					}
+				}
+			}
+		}
+	}
+	return snapshot
+}
+
+// The release groups and songs of an artist new since the previous check.
+type artistDigest struct {
+	Artist        string            `json:"artist"`
+	ID            mb2.MBID          `json:"id"`
+	Since         time.Time         `json:"since"`
+	ReleaseGroups []releaseGroupRef `json:"newReleaseGroups"`
+	Songs         []string          `json:"newSongs"`
+}
+
+type releaseGroupRef struct {
+	Title string   `json:"title"`
+	ID    mb2.MBID `json:"id"`
+}
+
+// Keeps the release groups and songs of the previous snapshot missing from this
+// one, so those missing from a single retrieval aren't reported as new again.
+func (s *watchSnapshot) keep(previous watchSnapshot) {
+	for id, title := range previous.Groups {
+		if _, ok := s.Groups[id]; !ok {
+			s.Groups[id] = title
+		}
+	}
+	for song, title := range previous.Songs {
+		if _, ok := s.Songs[song]; !ok {
+			s.Songs[song] = title
+		}
+	}
+}
+
+func diffWatchSnapshots(artistID mb2.MBID, previous, current watchSnapshot) artistDigest {
+	digest := artistDigest{
+		Artist:        current.Name,
+		ID:            artistID,
+		Since:         previous.Checked,
+		ReleaseGroups: []releaseGroupRef{},
+		Songs:         []string{},
+	}
+	for id, title := range current.Groups {
+		if _, ok := previous.Groups[id]; !ok {
+			digest.ReleaseGroups = append(digest.ReleaseGroups, releaseGroupRef{Title: title, ID: id})
+		}
+	}
+	for song, title := range current.Songs {
+		if _, ok := previous.Songs[song]; !ok {
+			digest.Songs = append(digest.Songs, title)
+		}
+	}
+	slices.SortFunc(digest.ReleaseGroups, func(a, b releaseGroupRef) int {
+		return cmp.Or(cmp.Compare(a.Title, b.Title), cmp.Compare(a.ID, b.ID))
+	})
+	slices.Sort(digest.Songs)
+	return digest
+}
+
+type watchDigest struct {
+	Checked time.Time      `json:"checked"`
+	Artists []artistDigest `json:"artists"`
+}
+
+// Writes the digest to the file at path, or standard output without one. An
+// RSS digest keeps the items of the feed already in the file.
+func writeWatchDigest(path string, format string, digest watchDigest) error {
+	var w io.Writer = os.Stdout
+	var items []rssItem
+	if path != "" {
+		if format == formatRSS {
+			feed, err := readRSSFeed(path)
+			if err != nil {
+				return err
+			}
+			items = feed.Channel.Items
+		}
+		f, err := os.Create(path)
+		if err != nil {
+			return fmt.Errorf(`creating digest: %w`, err)
+		}
+		defer f.Close()
+		w = f
+	}
+	switch format {
+	case formatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(digest)
+	case formatRSS:
+		return writeRSSFeed(w, newRSSFeed(digest, items))
+	}
+	printWatchDigest(w, digest)
+	return nil
+}
+
+func printWatchDigest(w io.Writer, digest watchDigest) {
+	if len(digest.Artists) == 0 {
+		fmt.Fprintln(messages, "No new music found")
+		return
+	}
+	fmt.Fprintln(w, "New Music,", digest.Checked.Format(time.DateTime))
+	fmt.Fprintln(w, horizontal)
+	for _, a := range digest.Artists {
+		fmt.Fprintf(w, "%v, since %v\n", a.Artist, a.Since.Format(time.DateTime))
+		if len(a.ReleaseGroups) > 0 {
+			fmt.Fprintln(w, "  New Release Groups:", strings.Join(releaseGroupTitles(a.ReleaseGroups), "; "))
+		}
+		if len(a.Songs) > 0 {
+			fmt.Fprintln(w, "  New Songs:", strings.Join(a.Songs, "; "))
+		}
+	}
+}
+
+func releaseGroupTitles(groups []releaseGroupRef) []string {
+	var titles []string
+	for _, rg := range groups {
+		titles = append(titles, rg.Title)
+	}
+	return titles
+}
+
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title       string    `xml:"title"`
+	Link        string    `xml:"link"`
+	Description string    `xml:"description"`
+	Items       []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string  `xml:"title"`
+	Link        string  `xml:"link"`
+	Description string  `xml:"description"`
+	GUID        rssGUID `xml:"guid"`
+	PubDate     string  `xml:"pubDate"`
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+// Returns a feed of an item for each artist in the digest, followed by the
+// previous items.
+func newRSSFeed(digest watchDigest, previous []rssItem) rssFeed {
+	feed := rssFeed{Version: "2.0", Channel: rssChannel{
+		Title:       "MusicGreed",
+		Link:        "https://musicbrainz.org",
+		Description: "Release groups and songs new to MusicBrainz for watched artists",
+	}}
+	for _, a := range digest.Artists {
+		var parts []string
+		if len(a.ReleaseGroups) > 0 {
+			parts = append(parts, "New release groups: "+strings.Join(releaseGroupTitles(a.ReleaseGroups), "; "))
+		}
+		if len(a.Songs) > 0 {
+			parts = append(parts, "New songs: "+strings.Join(a.Songs, "; "))
+		}
+		feed.Channel.Items = append(feed.Channel.Items, rssItem{
+			Title: fmt.Sprintf("%v: %v new release groups, %v new songs",
+				a.Artist, len(a.ReleaseGroups), len(a.Songs)),
+			Link:        "https://musicbrainz.org/artist/" + string(a.ID),
+			Description: strings.Join(parts, "\n"),
+			GUID:        rssGUID{Value: fmt.Sprintf("musicgreed:%v:%v", a.ID, digest.Checked.Unix())},
+			PubDate:     digest.Checked.Format(time.RFC1123Z),
+		})
+	}
+	feed.Channel.Items = append(feed.Channel.Items, previous...)
+	if len(feed.Channel.Items) > rssMaxItems {
+		feed.Channel.Items = feed.Channel.Items[:rssMaxItems]
+	}
+	return feed
+}
+
+func readRSSFeed(path string) (rssFeed, error) {
+	var feed rssFeed
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return feed, nil
+	} else if err != nil {
+		return feed, fmt.Errorf(`reading digest "%v": %w`, path, err)
+	}
+	if err := xml.Unmarshal(data, &feed); err != nil {
+		return feed, fmt.Errorf(`digest "%v" did not unmarshal cleanly: %w`, path, err)
+	}
+	return feed, nil
+}
+
+func writeRSSFeed(w io.Writer, feed rssFeed) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(feed); err != nil {
+		return fmt.Errorf(`encoding digest: %w`, err)
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+func defaultWatchStatePath() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf(`user cache directory not found: %w`, err)
+	}
+	return filepath.Join(dir, "musicgreed", "watch.json"), nil
+}
+
+func loadWatchSnapshots(path string) (map[mb2.MBID]watchSnapshot, error) {
+	snapshots := make(map[mb2.MBID]watchSnapshot)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return snapshots, nil
+	} else if err != nil {
+		return snapshots, fmt.Errorf(`reading watch snapshots "%v": %w`, path, err)
+	}
+	if err := json.Unmarshal(data, &snapshots); err != nil {
+		return snapshots, fmt.Errorf(`watch snapshots "%v" did not unmarshal cleanly: %w`, path, err)
+	}
+	return snapshots, nil
+}
+
+func saveWatchSnapshots(path string, snapshots map[mb2.MBID]watchSnapshot) error {
+	data, err := json.Marshal(snapshots)
+	if err != nil {
+		return fmt.Errorf(`marshaling watch snapshots: %w`, err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf(`creating watch snapshots directory: %w`, err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf(`writing watch snapshots "%v": %w`, tmp, err)
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/frigorific44/musicgreed/musicinfo"
+	mb2 "go.uploadedlobster.com/musicbrainzws2"
+)
+
+func TestWatchSnapshots(t *testing.T) {
+	artist := mb2.Artist{ID: "artist", Name: "Artist"}
+	group := func(id mb2.MBID, titles ...string) mb2.ReleaseGroup {
+		return mb2.ReleaseGroup{
+			ID:           id,
+			Title:        string(id),
+			ArtistCredit: mb2.ArtistCredit{{Name: "Artist", Artist: artist}},
+			Releases:     []mb2.Release{testRelease(id, titles...)},
+		}
+	}
+	first := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	previous := takeWatchSnapshot(artist.ID, []mb2.ReleaseGroup{group("album", "Song", "Other Song")}, musicinfo.DefaultAltTerms(), first)
+	if previous.Name != "Artist" || len(previous.Groups) != 1 || len(previous.Songs) != 2 {
+		t.Fatalf(`takeWatchSnapshot = %+v, wanted the credited name, one group, and two songs`, previous)
+	}
+	current := takeWatchSnapshot(artist.ID, []mb2.ReleaseGroup{
+		group("album", "Song", "Other Song"),
+		group("live", "Song (Live)", "SONG", "New Song"),
+	}, musicinfo.DefaultAltTerms(), first.Add(time.Hour))
+	digest := diffWatchSnapshots(artist.ID, previous, current)
+	if len(digest.ReleaseGroups) != 1 || digest.ReleaseGroups[0].ID != "live" ||
+		!slices.Equal(digest.Songs, []string{"New Song"}) || !digest.Since.Equal(first) {
+		t.Errorf(`diffWatchSnapshots = %+v, wanted the live group and only "New Song" since %v`, digest, first)
+	}
+
+	// A retrieval missing the album keeps it, so it isn't new when it returns.
+	partial := takeWatchSnapshot(artist.ID, []mb2.ReleaseGroup{group("live", "Song (Live)")}, musicinfo.DefaultAltTerms(), first.Add(2*time.Hour))
+	partial.keep(current)
+	if _, ok := partial.Groups["album"]; !ok || len(partial.Songs) != 3 {
+		t.Errorf(`keep = %+v, wanted the album and all three songs kept`, partial)
+	}
+}
+
+func TestWatchDigestRSS(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "digest.xml")
+	checked := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := range 2 {
+		digest := watchDigest{Checked: checked.Add(time.Duration(i) * time.Hour), Artists: []artistDigest{
+			{Artist: "Artist", ID: "artist", Songs: []string{"New Song"}},
+		}}
+		if err := writeWatchDigest(path, formatRSS, digest); err != nil {
+			t.Fatalf(`writeWatchDigest returned error: %v`, err)
+		}
+	}
+	feed, err := readRSSFeed(path)
+	if err != nil {
+		t.Fatalf(`readRSSFeed returned error: %v`, err)
+	}
+	items := feed.Channel.Items
+	if len(items) != 2 || items[0].GUID.Value == items[1].GUID.Value ||
+		items[0].PubDate != checked.Add(time.Hour).Format(time.RFC1123Z) {
+		t.Errorf(`digest feed items = %+v, wanted the newest of two items first`, items)
+	}
+}
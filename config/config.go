@@ -0,0 +1,133 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	fileName string = "config.yaml"
+)
+
+// Config holds command-line flag defaults, as well as named profiles of flags
+// to apply over them.
+//
+//	defaults:
+//	  dalt: true
+//	  alternate-terms:
+//	    German: [livemitschnitt]
+//	profiles:
+//	  strict:
+//	    dsec: [live, remix]
+//	    status: official
+type Config struct {
+	Defaults Profile            `yaml:"defaults"`
+	Profiles map[string]Profile `yaml:"profiles"`
+}
+
+// Profile maps flag names to their values, along with groups of terms marking
+// titles as alternate versions or not.
+type Profile struct {
+	AlternateTerms    map[string][]string `yaml:"alternate-terms"`
+	NotAlternateTerms map[string][]string `yaml:"not-alternate-terms"`
+	Flags             map[string]any      `yaml:",inline"`
+}
+
+// DefaultPath returns the path to the configuration file in the user's
+// configuration directory, such as $XDG_CONFIG_HOME/musicgreed/config.yaml.
+func DefaultPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf(`user configuration directory not found: %w`, err)
+	}
+	return filepath.Join(dir, "musicgreed", fileName), nil
+}
+
+// Load reads the configuration file at path. A missing file results in an
+// empty configuration.
+func Load(path string) (Config, error) {
+	var config Config
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return config, nil
+	} else if err != nil {
+		return config, fmt.Errorf(`reading configuration "%v": %w`, path, err)
+	}
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return config, fmt.Errorf(`configuration "%v" did not unmarshal cleanly: %w`, path, err)
+	}
+	return config, nil
+}
+
+// Resolve merges the named profile over the defaults, returning each flag's
+// value formatted as it would be given on the command line. An empty name
+// resolves the defaults alone.
+func (c Config) Resolve(name string) (map[string]string, error) {
+	flags := make(map[string]string)
+	profiles := []Profile{c.Defaults}
+	if name != "" {
+		profile, ok := c.Profiles[name]
+		if !ok {
+			var names []string
+			for n := range c.Profiles {
+				names = append(names, n)
+			}
+			slices.Sort(names)
+			return flags, fmt.Errorf(`profile %q not found in configuration, expected one of [%v]`, name, strings.Join(names, ", "))
+		}
+		profiles = append(profiles, profile)
+	}
+	for _, p := range profiles {
+		for flag, value := range p.Flags {
+			formatted, err := formatValue(value)
+			if err != nil {
+				return flags, fmt.Errorf(`flag %q: %w`, flag, err)
+			}
+			flags[flag] = formatted
+		}
+	}
+	return flags, nil
+}
+
+// TermGroups gathers the alternate and not-alternate term groups of the
+// defaults and the named profile.
+func (c Config) TermGroups(name string) (alt, notAlt map[string][]string) {
+	alt = make(map[string][]string)
+	notAlt = make(map[string][]string)
+	for _, p := range []Profile{c.Defaults, c.Profiles[name]} {
+		for group, terms := range p.AlternateTerms {
+			alt[group] = append(alt[group], terms...)
+		}
+		for group, terms := range p.NotAlternateTerms {
+			notAlt[group] = append(notAlt[group], terms...)
+		}
+	}
+	return alt, notAlt
+}
+
+func formatValue(value any) (string, error) {
+	switch v := value.(type) {
+	case []any:
+		items := make([]string, len(v))
+		for i, item := range v {
+			s, err := formatValue(item)
+			if err != nil {
+				return "", err
+			}
+			items[i] = s
+		}
+		return strings.Join(items, ","), nil
+	case map[string]any:
+		return "", fmt.Errorf(`a mapping is not a flag value`)
+	case nil:
+		return "", nil
+	}
+	return fmt.Sprint(value), nil
+}
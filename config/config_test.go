@@ -0,0 +1,113 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const testConfig = `
+defaults:
+  dalt: true
+  library: beets
+  alternate-terms:
+    German: [livemitschnitt]
+profiles:
+  strict:
+    dsec: [live, remix]
+    status: official
+    library: jellyfin
+    alternate-terms:
+      German: [unplugged-fassung]
+      Dutch: [levend]
+    not-alternate-terms:
+      Dutch: [intro]
+  nested:
+    dsec:
+      live: true
+`
+
+func TestResolve(t *testing.T) {
+	path := filepath.Join(t.TempDir(), fileName)
+	if err := os.WriteFile(path, []byte(testConfig), 0644); err != nil {
+		t.Fatal(err)
+	}
+	config, err := Load(path)
+	if err != nil {
+		t.Fatalf(`Load returned error: %v`, err)
+	}
+	cases := []struct {
+		Profile string
+		Want    map[string]string
+		Error   bool
+	}{
+		{"", map[string]string{"dalt": "true", "library": "beets"}, false},
+		{"strict", map[string]string{"dalt": "true", "library": "jellyfin", "dsec": "live,remix", "status": "official"}, false},
+		{"nested", nil, true},
+		{"missing", nil, true},
+	}
+	for _, c := range cases {
+		flags, err := config.Resolve(c.Profile)
+		if (err != nil) != c.Error {
+			t.Errorf(`Resolve(%q) returned error %v`, c.Profile, err)
+			continue
+		}
+		if c.Error {
+			continue
+		}
+		if len(flags) != len(c.Want) {
+			t.Errorf(`Resolve(%q) = %v, wanted %v`, c.Profile, flags, c.Want)
+		}
+		for k, v := range c.Want {
+			if flags[k] != v {
+				t.Errorf(`Resolve(%q) = %v, wanted %v`, c.Profile, flags, c.Want)
+			}
+		}
+	}
+}
+
+func TestLoadMissing(t *testing.T) {
+	config, err := Load(filepath.Join(t.TempDir(), fileName))
+	if err != nil {
+		t.Fatalf(`Load on a missing file returned error: %v`, err)
+	}
+	if flags, err := config.Resolve(""); err != nil || len(flags) != 0 {
+		t.Errorf(`Resolve on an empty configuration = %v, %v, wanted no flags`, flags, err)
+	}
+}
+
+func TestLoadInvalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), fileName)
+	if err := os.WriteFile(path, []byte("profiles: [strict"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(path); err == nil {
+		t.Error(`Load on an invalid file did not return an error`)
+	}
+}
+
+func TestTermGroups(t *testing.T) {
+	path := filepath.Join(t.TempDir(), fileName)
+	if err := os.WriteFile(path, []byte(testConfig), 0644); err != nil {
+		t.Fatal(err)
+	}
+	config, err := Load(path)
+	if err != nil {
+		t.Fatalf(`Load returned error: %v`, err)
+	}
+	cases := []struct {
+		Profile string
+		Alt     map[string][]string
+		NotAlt  map[string][]string
+	}{
+		{"", map[string][]string{"German": {"livemitschnitt"}}, map[string][]string{}},
+		{"strict", map[string][]string{"German": {"livemitschnitt", "unplugged-fassung"}, "Dutch": {"levend"}}, map[string][]string{"Dutch": {"intro"}}},
+	}
+	for _, c := range cases {
+		alt, notAlt := config.TermGroups(c.Profile)
+		if !reflect.DeepEqual(alt, c.Alt) || !reflect.DeepEqual(notAlt, c.NotAlt) {
+			t.Errorf(`TermGroups(%q) = %v, %v, wanted %v, %v`, c.Profile, alt, notAlt, c.Alt, c.NotAlt)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	watchlistFileName string = "watchlist.yaml"
+)
+
+// Watchlist holds the artists to check for new music.
+//
+//	artists:
+//	  - id: 65f4f0c5-ef9e-490c-aee3-909e7ae6b2ab
+//	    name: Metallica
+type Watchlist struct {
+	Artists []WatchedArtist `yaml:"artists"`
+}
+
+// WatchedArtist is an artist on the watchlist, by MusicBrainz ID.
+type WatchedArtist struct {
+	ID   string `yaml:"id"`
+	Name string `yaml:"name,omitempty"`
+}
+
+// DefaultWatchlistPath returns the path to the watchlist in the user's
+// configuration directory, beside the configuration file.
+func DefaultWatchlistPath() (string, error) {
+	path, err := DefaultPath()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(filepath.Dir(path), watchlistFileName), nil
+}
+
+// LoadWatchlist reads the watchlist at path. A missing file results in an
+// empty watchlist.
+func LoadWatchlist(path string) (Watchlist, error) {
+	var watchlist Watchlist
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return watchlist, nil
+	} else if err != nil {
+		return watchlist, fmt.Errorf(`reading watchlist "%v": %w`, path, err)
+	}
+	if err := yaml.Unmarshal(data, &watchlist); err != nil {
+		return watchlist, fmt.Errorf(`watchlist "%v" did not unmarshal cleanly: %w`, path, err)
+	}
+	return watchlist, nil
+}
+
+// Save writes the watchlist to path, creating its directory if needed.
+func (w Watchlist) Save(path string) error {
+	data, err := yaml.Marshal(w)
+	if err != nil {
+		return fmt.Errorf(`marshaling watchlist: %w`, err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf(`creating watchlist directory: %w`, err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf(`writing watchlist "%v": %w`, path, err)
+	}
+	return nil
+}
+
+// Add puts the artist on the watchlist, reporting whether it wasn't already.
+func (w *Watchlist) Add(artist WatchedArtist) bool {
+	if slices.ContainsFunc(w.Artists, func(a WatchedArtist) bool { return a.ID == artist.ID }) {
+		return false
+	}
+	w.Artists = append(w.Artists, artist)
+	return true
+}
+
+// Remove takes the artist with the ID or name off the watchlist, reporting
+// whether it was there.
+func (w *Watchlist) Remove(idOrName string) bool {
+	n := len(w.Artists)
+	w.Artists = slices.DeleteFunc(w.Artists, func(a WatchedArtist) bool {
+		return a.ID == idOrName || a.Name != "" && a.Name == idOrName
+	})
+	return len(w.Artists) < n
+}
@@ -0,0 +1,32 @@
+package config
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestWatchlist(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "musicgreed", watchlistFileName)
+	watchlist, err := LoadWatchlist(path)
+	if err != nil || len(watchlist.Artists) != 0 {
+		t.Fatalf(`LoadWatchlist on a missing file = %v, %v, wanted an empty watchlist`, watchlist, err)
+	}
+	if !watchlist.Add(WatchedArtist{ID: "id-a", Name: "A"}) || !watchlist.Add(WatchedArtist{ID: "id-b"}) {
+		t.Error(`Add of new artists reported them already watched`)
+	}
+	if watchlist.Add(WatchedArtist{ID: "id-a"}) {
+		t.Error(`Add of a watched artist reported it new`)
+	}
+	if err := watchlist.Save(path); err != nil {
+		t.Fatalf(`Save returned error: %v`, err)
+	}
+	loaded, err := LoadWatchlist(path)
+	if err != nil || !reflect.DeepEqual(loaded, watchlist) {
+		t.Errorf(`LoadWatchlist after Save = %v, %v, wanted %v`, loaded, err, watchlist)
+	}
+
+	if !loaded.Remove("A") || loaded.Remove("A") || !loaded.Remove("id-b") || len(loaded.Artists) != 0 {
+		t.Errorf(`Remove by name and ID left %v`, loaded.Artists)
+	}
+}
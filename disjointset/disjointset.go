@@ -0,0 +1,83 @@
+// Package disjointset provides a union-find structure, partitioning elements
+// into groups which stay transitive however unions chain.
+package disjointset
+
+// Set partitions elements into disjoint groups. The zero value is not usable;
+// create sets with New.
+type Set[T comparable] struct {
+	parent map[T]T
+	rank   map[T]int
+}
+
+func New[T comparable]() *Set[T] {
+	return &Set[T]{parent: make(map[T]T), rank: make(map[T]int)}
+}
+
+// Add places the element in a group of its own, if not already in the set.
+func (s *Set[T]) Add(x T) {
+	if _, ok := s.parent[x]; !ok {
+		s.parent[x] = x
+	}
+}
+
+// Find returns the representative of the element's group, adding the element
+// if not already in the set.
+func (s *Set[T]) Find(x T) T {
+	s.Add(x)
+	root := x
+	for s.parent[root] != root {
+		root = s.parent[root]
+	}
+	// Compress the path, so later finds are quicker.
+	for x != root {
+		next := s.parent[x]
+		s.parent[x] = root
+		x = next
+	}
+	return root
+}
+
+// Union joins the groups of the elements, reporting whether they were apart.
+func (s *Set[T]) Union(x, y T) bool {
+	rootX, rootY := s.Find(x), s.Find(y)
+	if rootX == rootY {
+		return false
+	}
+	switch {
+	case s.rank[rootX] < s.rank[rootY]:
+		s.parent[rootX] = rootY
+	case s.rank[rootX] > s.rank[rootY]:
+		s.parent[rootY] = rootX
+	default:
+		s.parent[rootY] = rootX
+		s.rank[rootX]++
+	}
+	return true
+}
+
+// Same reports whether the elements are in the same group.
+func (s *Set[T]) Same(x, y T) bool {
+	return s.Find(x) == s.Find(y)
+}
+
+// Len returns the number of elements in the set.
+func (s *Set[T]) Len() int {
+	return len(s.parent)
+}
+
+// Groups returns every group of the set, in no particular order.
+func (s *Set[T]) Groups() [][]T {
+	index := make(map[T]int)
+	var groups [][]T
+	for x := range s.parent {
+		root := s.Find(x)
+		i, ok := index[root]
+		if !ok {
+			i = len(groups)
+			index[root] = i
+			groups = append(groups, nil)
+		}
+		groups[i] = append(groups[i], x)
+	}
+	return groups
+}
@@ -0,0 +1,71 @@
+package disjointset
+
+import (
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+func TestUnion(t *testing.T) {
+	s := New[string]()
+	s.Add("e")
+	cases := []struct {
+		X, Y string
+		Want bool
+	}{
+		{"a", "b", true},
+		{"c", "d", true},
+		{"b", "a", false},
+		{"d", "a", true},
+		{"c", "b", false},
+	}
+	for _, c := range cases {
+		if res := s.Union(c.X, c.Y); res != c.Want {
+			t.Errorf(`Union(%v, %v) = %v, wanted %v`, c.X, c.Y, res, c.Want)
+		}
+	}
+	if !s.Same("a", "c") || s.Same("a", "e") {
+		t.Errorf(`Same did not reflect the chained unions`)
+	}
+	groups := s.Groups()
+	for _, g := range groups {
+		slices.Sort(g)
+	}
+	slices.SortFunc(groups, func(a, b []string) int { return slices.Compare(a, b) })
+	want := [][]string{{"a", "b", "c", "d"}, {"e"}}
+	if len(groups) != len(want) || !slices.Equal(groups[0], want[0]) || !slices.Equal(groups[1], want[1]) {
+		t.Errorf(`Groups() = %v, wanted %v`, groups, want)
+	}
+	if s.Len() != 5 {
+		t.Errorf(`Len() = %v, wanted 5`, s.Len())
+	}
+}
+
+// Compares against labeling each element with its group and relabeling whole
+// groups on every union.
+func TestTransitive(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	s := New[int]()
+	labels := make([]int, 200)
+	for i := range labels {
+		labels[i] = i
+		s.Add(i)
+	}
+	for n := 0; n < 150; n++ {
+		x, y := r.Intn(len(labels)), r.Intn(len(labels))
+		s.Union(x, y)
+		from, to := labels[y], labels[x]
+		for i := range labels {
+			if labels[i] == from {
+				labels[i] = to
+			}
+		}
+	}
+	for x := range labels {
+		for y := range labels {
+			if s.Same(x, y) != (labels[x] == labels[y]) {
+				t.Fatalf(`Same(%v, %v) = %v, wanted %v`, x, y, s.Same(x, y), labels[x] == labels[y])
+			}
+		}
+	}
+}
@@ -0,0 +1,330 @@
+// Package expr implements a small expression language for selecting releases
+// and tracks, such as:
+//
+//	release.country in ["US", "GB"] and not track.title ~ "(?i)remaster"
+//
+// Expressions combine comparisons (==, !=, <, <=, >, >=), regular expression
+// matches (~, !~), and list membership (in) with and, or, not, and parentheses.
+// Operands are field names, quoted strings, numbers, true, false, and lists of
+// these in square brackets. Field values are supplied by an Env on evaluation.
+package expr
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Value is one of string, float64, bool, or []Value.
+type Value any
+
+// Env supplies the values of fields named in an expression.
+type Env interface {
+	Lookup(field string) (Value, bool)
+}
+
+// MapEnv is an Env backed by a map of field names to values.
+type MapEnv map[string]Value
+
+func (env MapEnv) Lookup(field string) (Value, bool) {
+	v, ok := env[field]
+	return v, ok
+}
+
+// Expr is a parsed expression.
+type Expr struct {
+	src  string
+	root node
+}
+
+// Parse parses the source of an expression.
+func Parse(src string) (*Expr, error) {
+	tokens, err := lex(src)
+	if err != nil {
+		return nil, err
+	}
+	p := parser{tokens: tokens}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokEOF {
+		return nil, &Error{Pos: tok.pos, Msg: fmt.Sprintf("unexpected %v", tok)}
+	}
+	return &Expr{src: src, root: root}, nil
+}
+
+func (e *Expr) String() string {
+	return e.src
+}
+
+// Fields returns the distinct field names referenced by the expression.
+func (e *Expr) Fields() []string {
+	var fields []string
+	e.root.walk(func(n node) {
+		if f, ok := n.(fieldNode); ok && !slices.Contains(fields, f.name) {
+			fields = append(fields, f.name)
+		}
+	})
+	return fields
+}
+
+// Eval evaluates the expression in the environment, which must result in a bool.
+func (e *Expr) Eval(env Env) (bool, error) {
+	v, err := e.root.eval(env)
+	if err != nil {
+		return false, err
+	}
+	b, ok := v.(bool)
+	if !ok {
+		return false, &Error{Pos: e.root.position(), Msg: fmt.Sprintf("expression results in %v, not a bool", typeName(v))}
+	}
+	return b, nil
+}
+
+// Error reports a problem with an expression at a byte offset of its source.
+type Error struct {
+	Pos int
+	Msg string
+}
+
+func (err *Error) Error() string {
+	return fmt.Sprintf("at position %v: %v", err.Pos+1, err.Msg)
+}
+
+type node interface {
+	eval(env Env) (Value, error)
+	position() int
+	walk(fn func(node))
+}
+
+type literalNode struct {
+	pos   int
+	value Value
+}
+
+func (n literalNode) eval(Env) (Value, error) { return n.value, nil }
+func (n literalNode) position() int           { return n.pos }
+func (n literalNode) walk(fn func(node))      { fn(n) }
+
+type fieldNode struct {
+	pos  int
+	name string
+}
+
+func (n fieldNode) eval(env Env) (Value, error) {
+	v, ok := env.Lookup(n.name)
+	if !ok {
+		return nil, &Error{Pos: n.pos, Msg: fmt.Sprintf("unknown field %q", n.name)}
+	}
+	return v, nil
+}
+func (n fieldNode) position() int      { return n.pos }
+func (n fieldNode) walk(fn func(node)) { fn(n) }
+
+type listNode struct {
+	pos   int
+	items []node
+}
+
+func (n listNode) eval(env Env) (Value, error) {
+	list := make([]Value, 0, len(n.items))
+	for _, item := range n.items {
+		v, err := item.eval(env)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, v)
+	}
+	return list, nil
+}
+func (n listNode) position() int { return n.pos }
+func (n listNode) walk(fn func(node)) {
+	fn(n)
+	for _, item := range n.items {
+		item.walk(fn)
+	}
+}
+
+type notNode struct {
+	pos     int
+	operand node
+}
+
+func (n notNode) eval(env Env) (Value, error) {
+	b, err := evalBool(n.operand, env)
+	return !b, err
+}
+func (n notNode) position() int { return n.pos }
+func (n notNode) walk(fn func(node)) {
+	fn(n)
+	n.operand.walk(fn)
+}
+
+type logicalNode struct {
+	pos         int
+	and         bool
+	left, right node
+}
+
+func (n logicalNode) eval(env Env) (Value, error) {
+	left, err := evalBool(n.left, env)
+	if err != nil {
+		return nil, err
+	}
+	// Short-circuit
+	if left != n.and {
+		return left, nil
+	}
+	return evalBool(n.right, env)
+}
+func (n logicalNode) position() int { return n.pos }
+func (n logicalNode) walk(fn func(node)) {
+	fn(n)
+	n.left.walk(fn)
+	n.right.walk(fn)
+}
+
+type compareNode struct {
+	pos         int
+	op          string
+	left, right node
+	// Compiled ahead of time when the pattern is a literal.
+	exp *regexp.Regexp
+}
+
+func (n compareNode) eval(env Env) (Value, error) {
+	left, err := n.left.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	right, err := n.right.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	switch n.op {
+	case "in":
+		list, ok := right.([]Value)
+		if !ok {
+			return nil, n.errorf("right of in must be a list, not %v", typeName(right))
+		}
+		return anyOf(left, func(v Value) bool {
+			return slices.ContainsFunc(list, func(el Value) bool { return equal(v, el) })
+		}), nil
+	case "~", "!~":
+		exp := n.exp
+		if exp == nil {
+			pattern, ok := right.(string)
+			if !ok {
+				return nil, n.errorf("right of %v must be a string, not %v", n.op, typeName(right))
+			}
+			if exp, err = regexp.Compile(pattern); err != nil {
+				return nil, n.errorf("invalid regular expression: %v", err)
+			}
+		}
+		var typeErr error
+		matched := anyOf(left, func(v Value) bool {
+			s, ok := v.(string)
+			if !ok {
+				typeErr = n.errorf("left of %v must be a string, not %v", n.op, typeName(v))
+				return false
+			}
+			return exp.MatchString(s)
+		})
+		if typeErr != nil {
+			return nil, typeErr
+		}
+		return matched == (n.op == "~"), nil
+	case "==":
+		return equal(left, right), nil
+	case "!=":
+		return !equal(left, right), nil
+	}
+	c, err := n.order(left, right)
+	if err != nil {
+		return nil, err
+	}
+	switch n.op {
+	case "<":
+		return c < 0, nil
+	case "<=":
+		return c <= 0, nil
+	case ">":
+		return c > 0, nil
+	}
+	return c >= 0, nil
+}
+
+func (n compareNode) order(left, right Value) (int, error) {
+	switch l := left.(type) {
+	case float64:
+		if r, ok := right.(float64); ok {
+			switch {
+			case l < r:
+				return -1, nil
+			case l > r:
+				return 1, nil
+			}
+			return 0, nil
+		}
+	case string:
+		if r, ok := right.(string); ok {
+			return strings.Compare(l, r), nil
+		}
+	}
+	return 0, n.errorf("cannot order %v and %v with %v", typeName(left), typeName(right), n.op)
+}
+
+func (n compareNode) errorf(format string, a ...any) error {
+	return &Error{Pos: n.pos, Msg: fmt.Sprintf(format, a...)}
+}
+func (n compareNode) position() int { return n.pos }
+func (n compareNode) walk(fn func(node)) {
+	fn(n)
+	n.left.walk(fn)
+	n.right.walk(fn)
+}
+
+func evalBool(n node, env Env) (bool, error) {
+	v, err := n.eval(env)
+	if err != nil {
+		return false, err
+	}
+	b, ok := v.(bool)
+	if !ok {
+		return false, &Error{Pos: n.position(), Msg: fmt.Sprintf("expected a bool, not %v", typeName(v))}
+	}
+	return b, nil
+}
+
+// A list on the left of a comparison matches if any of its elements do.
+func anyOf(v Value, match func(Value) bool) bool {
+	if list, ok := v.([]Value); ok {
+		return slices.ContainsFunc(list, match)
+	}
+	return match(v)
+}
+
+func equal(a, b Value) bool {
+	la, okA := a.([]Value)
+	lb, okB := b.([]Value)
+	if okA || okB {
+		return okA && okB && slices.EqualFunc(la, lb, equal)
+	}
+	return a == b
+}
+
+func typeName(v Value) string {
+	switch v.(type) {
+	case string:
+		return "string"
+	case float64:
+		return "number"
+	case bool:
+		return "bool"
+	case []Value:
+		return "list"
+	}
+	return fmt.Sprintf("%T", v)
+}
@@ -0,0 +1,153 @@
+package expr
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+var testEnv = MapEnv{
+	"release.country": "US",
+	"release.year":    float64(2004),
+	"release.formats": []Value{"CD", `12" Vinyl`},
+	"track.title":     "Song (2011 Remaster)",
+	"track.video":     false,
+}
+
+func TestEval(t *testing.T) {
+	cases := []struct {
+		Src  string
+		Want bool
+	}{
+		{`release.country == "US"`, true},
+		{`release.country != 'US'`, false},
+		{`release.country in ["US", "GB"]`, true},
+		{`release.country in []`, false},
+		{`release.year >= 2000 and release.year < 2005`, true},
+		{`release.year > 2004`, false},
+		{`release.year <= -1`, false},
+		{`"CD" in release.formats`, true},
+		{`release.formats in ["Cassette", "CD"]`, true},
+		{`release.formats ~ "(?i)vinyl"`, true},
+		{`release.formats !~ "Vinyl"`, false},
+		{`track.title ~ "(?i)remaster"`, true},
+		{`track.title ~ "\d{4}"`, true},
+		{`track.title ~ "\"" or track.title == 'Song (2011 Remaster)'`, true},
+		{`not track.title ~ "(?i)remaster"`, false},
+		{`not not track.video`, false},
+		{`release.country in ["US","GB"] and not track.title ~ "(?i)remaster"`, false},
+		{`release.country == "GB" or (release.year == 2004 and not track.video)`, true},
+		{`true and false or true`, true},
+		{`true or false and false`, true},
+		{`track.video == false`, true},
+	}
+	for _, c := range cases {
+		e, err := Parse(c.Src)
+		if err != nil {
+			t.Errorf(`Parse(%q) returned error: %v`, c.Src, err)
+			continue
+		}
+		res, err := e.Eval(testEnv)
+		if err != nil {
+			t.Errorf(`Eval(%q) returned error: %v`, c.Src, err)
+		} else if res != c.Want {
+			t.Errorf(`Eval(%q) = %v, wanted %v`, c.Src, res, c.Want)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	cases := []struct {
+		Src string
+		Pos int
+	}{
+		{``, 0},
+		{`release.country ==`, 18},
+		{`release.country == "US`, 19},
+		{`release.country in "US"`, 19},
+		{`(release.year > 2000`, 20},
+		{`release.country in ["US" "GB"]`, 25},
+		{`track.title ~ "(unclosed"`, 14},
+		{`track.title ~ 12`, 14},
+		{`release.year > 2000 release.year`, 20},
+		{`release.country = "US"`, 16},
+		{`and true`, 0},
+	}
+	for _, c := range cases {
+		_, err := Parse(c.Src)
+		var exprErr *Error
+		if !errors.As(err, &exprErr) {
+			t.Errorf(`Parse(%q) returned %v, wanted an *Error`, c.Src, err)
+		} else if exprErr.Pos != c.Pos {
+			t.Errorf(`Parse(%q) returned error at %v (%v), wanted at %v`, c.Src, exprErr.Pos, exprErr, c.Pos)
+		}
+	}
+}
+
+func TestLex(t *testing.T) {
+	cases := []struct {
+		Src   string
+		Want  []string
+		Error int
+	}{
+		{`release.year >= -20.5`, []string{"release.year", ">=", "-20.5"}, -1},
+		{`track.title٣ == "٣"`, []string{"track.title٣", "==", "٣"}, -1},
+		// Digits outside ASCII aren't numbers, so are rejected by their byte position.
+		{`release.year > ٣`, nil, 15},
+		{`٣٣ == 1`, nil, 0},
+	}
+	for _, c := range cases {
+		tokens, err := lex(c.Src)
+		if c.Error >= 0 {
+			var exprErr *Error
+			if !errors.As(err, &exprErr) || exprErr.Pos != c.Error {
+				t.Errorf(`lex(%q) returned error %v, wanted one at %v`, c.Src, err, c.Error)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf(`lex(%q) returned error: %v`, c.Src, err)
+			continue
+		}
+		var texts []string
+		for _, tok := range tokens {
+			if tok.kind != tokEOF {
+				texts = append(texts, tok.text)
+			}
+		}
+		if !slices.Equal(texts, c.Want) {
+			t.Errorf(`lex(%q) = %q, wanted %q`, c.Src, texts, c.Want)
+		}
+	}
+}
+
+func TestEvalErrors(t *testing.T) {
+	cases := []string{
+		`release.title == "A"`,
+		`release.country`,
+		`release.year < "2000"`,
+		`release.year ~ "2000"`,
+		`release.country in release.year`,
+		`not release.country`,
+	}
+	for _, src := range cases {
+		e, err := Parse(src)
+		if err != nil {
+			t.Errorf(`Parse(%q) returned error: %v`, src, err)
+			continue
+		}
+		if _, err := e.Eval(testEnv); err == nil {
+			t.Errorf(`Eval(%q) did not return an error`, src)
+		}
+	}
+}
+
+func TestFields(t *testing.T) {
+	e, err := Parse(`release.country in ["US"] and (track.title ~ "x" or release.country == "GB")`)
+	if err != nil {
+		t.Fatalf(`Parse returned error: %v`, err)
+	}
+	if fields := e.Fields(); !slices.Equal(fields, []string{"release.country", "track.title"}) {
+		t.Errorf(`Fields() = %v, wanted [release.country track.title]`, fields)
+	}
+}
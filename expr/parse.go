@@ -0,0 +1,280 @@
+package expr
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokIdent
+	tokString
+	tokNumber
+	tokOp
+	tokPunct
+)
+
+type token struct {
+	kind tokenKind
+	pos  int
+	text string
+}
+
+func (tok token) String() string {
+	switch tok.kind {
+	case tokEOF:
+		return "end of expression"
+	case tokString:
+		return strconv.Quote(tok.text)
+	}
+	return fmt.Sprintf("%q", tok.text)
+}
+
+var keywords = map[string]bool{"and": true, "or": true, "not": true, "in": true, "true": true, "false": true}
+
+func lex(src string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(src); {
+		r, size := utf8.DecodeRuneInString(src[i:])
+		switch {
+		case unicode.IsSpace(r):
+			i += size
+		case r == '"' || r == '\'':
+			s, n, err := lexString(src[i:])
+			if err != nil {
+				return nil, &Error{Pos: i, Msg: err.Error()}
+			}
+			tokens = append(tokens, token{kind: tokString, pos: i, text: s})
+			i += n
+		case r >= '0' && r <= '9' || (r == '-' && i+1 < len(src) && src[i+1] >= '0' && src[i+1] <= '9'):
+			j := i + 1
+			for j < len(src) && (src[j] >= '0' && src[j] <= '9' || src[j] == '.') {
+				j++
+			}
+			tokens = append(tokens, token{kind: tokNumber, pos: i, text: src[i:j]})
+			i = j
+		case unicode.IsLetter(r) || r == '_':
+			j := i
+			for j < len(src) {
+				r, size := utf8.DecodeRuneInString(src[j:])
+				if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' && r != '.' {
+					break
+				}
+				j += size
+			}
+			tokens = append(tokens, token{kind: tokIdent, pos: i, text: src[i:j]})
+			i = j
+		default:
+			if op := lexOp(src[i:]); op != "" {
+				tokens = append(tokens, token{kind: tokOp, pos: i, text: op})
+				i += len(op)
+			} else if strings.ContainsRune("()[],", r) {
+				tokens = append(tokens, token{kind: tokPunct, pos: i, text: string(r)})
+				i += size
+			} else {
+				return nil, &Error{Pos: i, Msg: fmt.Sprintf("unexpected character %q", r)}
+			}
+		}
+	}
+	return append(tokens, token{kind: tokEOF, pos: len(src)}), nil
+}
+
+func lexOp(src string) string {
+	for _, op := range []string{"==", "!=", "<=", ">=", "!~", "<", ">", "~"} {
+		if strings.HasPrefix(src, op) {
+			return op
+		}
+	}
+	return ""
+}
+
+// Returns the unquoted string and the length of its quoted source.
+func lexString(src string) (string, int, error) {
+	quote := src[0]
+	var sb strings.Builder
+	for i := 1; i < len(src); i++ {
+		switch c := src[i]; c {
+		case quote:
+			return sb.String(), i + 1, nil
+		case '\\':
+			// Escapes other than quotes are kept, as they are common in regular expressions.
+			if i+1 < len(src) && src[i+1] == quote {
+				i++
+				sb.WriteByte(quote)
+			} else {
+				sb.WriteByte(c)
+			}
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	return "", 0, fmt.Errorf("unterminated string")
+}
+
+type parser struct {
+	tokens []token
+	i      int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.i]
+}
+
+func (p *parser) next() token {
+	tok := p.tokens[p.i]
+	if tok.kind != tokEOF {
+		p.i++
+	}
+	return tok
+}
+
+func (p *parser) isKeyword(word string) bool {
+	tok := p.peek()
+	return tok.kind == tokIdent && tok.text == word
+}
+
+func (p *parser) expect(kind tokenKind, text string) (token, error) {
+	tok := p.next()
+	if tok.kind != kind || tok.text != text {
+		return tok, &Error{Pos: tok.pos, Msg: fmt.Sprintf("expected %q, found %v", text, tok)}
+	}
+	return tok, nil
+}
+
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.isKeyword("or") {
+		tok := p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = logicalNode{pos: tok.pos, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.isKeyword("and") {
+		tok := p.next()
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = logicalNode{pos: tok.pos, and: true, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseNot() (node, error) {
+	if p.isKeyword("not") {
+		tok := p.next()
+		operand, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{pos: tok.pos, operand: operand}, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *parser) parseComparison() (node, error) {
+	left, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	tok := p.peek()
+	if tok.kind != tokOp && !p.isKeyword("in") {
+		return left, nil
+	}
+	p.next()
+	right, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	cmp := compareNode{pos: tok.pos, op: tok.text, left: left, right: right}
+	if lit, ok := right.(literalNode); ok && (cmp.op == "~" || cmp.op == "!~") {
+		pattern, ok := lit.value.(string)
+		if !ok {
+			return nil, &Error{Pos: lit.pos, Msg: fmt.Sprintf("right of %v must be a string, not %v", cmp.op, typeName(lit.value))}
+		}
+		if cmp.exp, err = regexp.Compile(pattern); err != nil {
+			return nil, &Error{Pos: lit.pos, Msg: fmt.Sprintf("invalid regular expression: %v", err)}
+		}
+	}
+	if _, ok := right.(listNode); !ok && cmp.op == "in" {
+		if _, ok := right.(fieldNode); !ok {
+			return nil, &Error{Pos: right.position(), Msg: "right of in must be a list"}
+		}
+	}
+	return cmp, nil
+}
+
+func (p *parser) parseOperand() (node, error) {
+	tok := p.next()
+	switch tok.kind {
+	case tokString:
+		return literalNode{pos: tok.pos, value: tok.text}, nil
+	case tokNumber:
+		f, err := strconv.ParseFloat(tok.text, 64)
+		if err != nil {
+			return nil, &Error{Pos: tok.pos, Msg: fmt.Sprintf("invalid number %q", tok.text)}
+		}
+		return literalNode{pos: tok.pos, value: f}, nil
+	case tokIdent:
+		switch tok.text {
+		case "true", "false":
+			return literalNode{pos: tok.pos, value: tok.text == "true"}, nil
+		}
+		if keywords[tok.text] {
+			break
+		}
+		return fieldNode{pos: tok.pos, name: tok.text}, nil
+	case tokPunct:
+		switch tok.text {
+		case "(":
+			inner, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			if _, err := p.expect(tokPunct, ")"); err != nil {
+				return nil, err
+			}
+			return inner, nil
+		case "[":
+			list := listNode{pos: tok.pos}
+			if p.peek().kind == tokPunct && p.peek().text == "]" {
+				p.next()
+				return list, nil
+			}
+			for {
+				item, err := p.parseOperand()
+				if err != nil {
+					return nil, err
+				}
+				list.items = append(list.items, item)
+				sep := p.next()
+				if sep.kind == tokPunct && sep.text == "]" {
+					return list, nil
+				}
+				if sep.kind != tokPunct || sep.text != "," {
+					return nil, &Error{Pos: sep.pos, Msg: fmt.Sprintf(`expected "," or "]", found %v`, sep)}
+				}
+			}
+		}
+	}
+	return nil, &Error{Pos: tok.pos, Msg: fmt.Sprintf("expected a field, value, or list, found %v", tok)}
+}
@@ -6,6 +6,8 @@ require (
 	github.com/adrg/strutil v0.3.1
 	github.com/spf13/cobra v1.8.0
 	go.uploadedlobster.com/musicbrainzws2 v0.9.1
+	golang.org/x/text v0.15.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -15,5 +17,4 @@ require (
 	github.com/russross/blackfriday/v2 v2.1.0 // indirect
 	github.com/spf13/pflag v1.0.5 // indirect
 	golang.org/x/net v0.25.0 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
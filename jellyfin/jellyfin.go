@@ -0,0 +1,152 @@
+package jellyfin
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	mb2 "go.uploadedlobster.com/musicbrainzws2"
+)
+
+const (
+	pageLimit int = 500
+	// Jellyfin reports runtimes in ticks of 100 nanoseconds.
+	tickDuration time.Duration = 100 * time.Nanosecond
+)
+
+var (
+	artistProviderKeys = []string{"MusicBrainzArtist", "MusicBrainzAlbumArtist"}
+)
+
+type Client struct {
+	URL        string
+	APIKey     string
+	HTTPClient *http.Client
+	// The audio items of the whole library, retrieved on first use, as the
+	// server can't filter items by MusicBrainz provider ID.
+	items []audioItem
+}
+
+func NewClient(serverURL string, apiKey string) *Client {
+	return &Client{
+		URL:        strings.TrimRight(serverURL, "/"),
+		APIKey:     apiKey,
+		HTTPClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// ArtistTrackTitles returns the audio items of the server library credited to
+// the artist, identified by the MusicBrainz provider IDs stored on each item.
+// The library is retrieved once per client, however many artists are asked for.
+func (c *Client) ArtistTrackTitles(id mb2.MBID) ([]mb2.Track, error) {
+	var tracks []mb2.Track
+	if c.items == nil {
+		items, err := c.libraryItems()
+		if err != nil {
+			return tracks, err
+		}
+		c.items = items
+	}
+	for _, item := range c.items {
+		if item.creditsArtist(id) {
+			tracks = append(tracks, item.track())
+		}
+	}
+	return tracks, nil
+}
+
+// Pages through every audio item of the library.
+func (c *Client) libraryItems() ([]audioItem, error) {
+	items := []audioItem{}
+	if c.URL == "" {
+		return items, fmt.Errorf(`jellyfin server URL not set`)
+	}
+	for {
+		page, err := c.audioItems(len(items))
+		if err != nil {
+			return items, err
+		}
+		items = append(items, page.Items...)
+		if len(page.Items) == 0 || len(items) >= page.TotalRecordCount {
+			break
+		}
+	}
+	return items, nil
+}
+
+func (c *Client) audioItems(start int) (itemsResult, error) {
+	var result itemsResult
+	query := url.Values{}
+	query.Set("Recursive", "true")
+	query.Set("IncludeItemTypes", "Audio")
+	query.Set("Fields", "ProviderIds")
+	query.Set("StartIndex", strconv.Itoa(start))
+	query.Set("Limit", strconv.Itoa(pageLimit))
+	req, err := http.NewRequest(http.MethodGet, c.URL+"/Items?"+query.Encode(), nil)
+	if err != nil {
+		return result, fmt.Errorf(`building jellyfin request: %w`, err)
+	}
+	req.Header.Set("Accept", "application/json")
+	if c.APIKey != "" {
+		req.Header.Set("X-Emby-Token", c.APIKey)
+	}
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return result, fmt.Errorf(`request "%v" failed: %w`, req.URL, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return result, fmt.Errorf(`request "%v" returned status %v`, req.URL, resp.Status)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return result, fmt.Errorf(`request "%v" did not unmarshal cleanly: %w`, req.URL, err)
+	}
+	return result, nil
+}
+
+type itemsResult struct {
+	Items            []audioItem `json:"Items"`
+	TotalRecordCount int         `json:"TotalRecordCount"`
+}
+
+type audioItem struct {
+	Name         string            `json:"Name"`
+	RunTimeTicks int64             `json:"RunTimeTicks"`
+	IndexNumber  int               `json:"IndexNumber"`
+	ProviderIds  map[string]string `json:"ProviderIds"`
+}
+
+func (item audioItem) creditsArtist(id mb2.MBID) bool {
+	for _, key := range artistProviderKeys {
+		for _, pid := range splitProviderIDs(item.ProviderIds[key]) {
+			if strings.EqualFold(pid, string(id)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (item audioItem) track() mb2.Track {
+	return mb2.Track{
+		ID:       mb2.MBID(item.ProviderIds["MusicBrainzTrack"]),
+		Title:    item.Name,
+		Length:   mb2.Duration{Duration: time.Duration(item.RunTimeTicks) * tickDuration},
+		Position: item.IndexNumber,
+		Recording: mb2.Recording{
+			ID:    mb2.MBID(item.ProviderIds["MusicBrainzRecording"]),
+			Title: item.Name,
+		},
+	}
+}
+
+// Multiple artist IDs may be stored in a single provider ID value.
+func splitProviderIDs(value string) []string {
+	return strings.FieldsFunc(value, func(r rune) bool {
+		return r == '/' || r == ';' || r == ',' || r == ' '
+	})
+}
@@ -0,0 +1,79 @@
+package jellyfin
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	mb2 "go.uploadedlobster.com/musicbrainzws2"
+)
+
+func TestArtistTrackTitles(t *testing.T) {
+	artist := mb2.MBID("8682866a-4f7a-43f5-83b2-06eabd0f2d4c")
+	items := []audioItem{
+		{Name: "A", RunTimeTicks: 710000000, IndexNumber: 1, ProviderIds: map[string]string{
+			"MusicBrainzArtist":    string(artist),
+			"MusicBrainzTrack":     "00000000-0000-0000-0000-000000000001",
+			"MusicBrainzRecording": "00000000-0000-0000-0000-000000000011",
+		}},
+		{Name: "B", RunTimeTicks: 1800000000, IndexNumber: 2, ProviderIds: map[string]string{
+			"MusicBrainzArtist":      "ca1b4c5d-21bd-45aa-879f-60bbeb10e91e",
+			"MusicBrainzAlbumArtist": "ca1b4c5d-21bd-45aa-879f-60bbeb10e91e/" + string(artist),
+			"MusicBrainzTrack":       "00000000-0000-0000-0000-000000000002",
+		}},
+		{Name: "C", RunTimeTicks: 1200000000, IndexNumber: 3, ProviderIds: map[string]string{
+			"MusicBrainzArtist": "ca1b4c5d-21bd-45aa-879f-60bbeb10e91e",
+		}},
+		{Name: "D", IndexNumber: 4},
+	}
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests += 1
+		if r.Header.Get("X-Emby-Token") != "key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		// Serve single-item pages to exercise pagination.
+		start, _ := strconv.Atoi(r.URL.Query().Get("StartIndex"))
+		page := itemsResult{TotalRecordCount: len(items)}
+		if start < len(items) {
+			page.Items = items[start : start+1]
+		}
+		json.NewEncoder(w).Encode(page)
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL+"/", "key")
+	tracks, err := client.ArtistTrackTitles(artist)
+	if err != nil {
+		t.Fatalf(`ArtistTrackTitles returned error: %v`, err)
+	}
+	want := []mb2.Track{
+		{ID: "00000000-0000-0000-0000-000000000001", Title: "A", Length: mb2.Duration{Duration: 71 * time.Second}, Position: 1},
+		{ID: "00000000-0000-0000-0000-000000000002", Title: "B", Length: mb2.Duration{Duration: 180 * time.Second}, Position: 2},
+	}
+	if len(tracks) != len(want) {
+		t.Fatalf(`ArtistTrackTitles returned %+v, wanted %+v`, tracks, want)
+	}
+	for i := range want {
+		if tracks[i].ID != want[i].ID || tracks[i].Title != want[i].Title || tracks[i].Length.Duration != want[i].Length.Duration || tracks[i].Position != want[i].Position {
+			t.Errorf(`ArtistTrackTitles returned %+v, wanted %+v`, tracks[i], want[i])
+		}
+	}
+	if tracks[0].Recording.ID != "00000000-0000-0000-0000-000000000011" {
+		t.Errorf(`ArtistTrackTitles returned recording ID %v, wanted the MusicBrainzRecording provider ID`, tracks[0].Recording.ID)
+	}
+
+	// The library is retrieved once, not again for every artist.
+	requests = 0
+	if other, err := client.ArtistTrackTitles("ca1b4c5d-21bd-45aa-879f-60bbeb10e91e"); err != nil || len(other) != 2 || requests != 0 {
+		t.Errorf(`ArtistTrackTitles for a second artist = %v, %v after %v requests, wanted B and C without requests`, other, err, requests)
+	}
+
+	if _, err := NewClient(server.URL, "wrong").ArtistTrackTitles(artist); err == nil {
+		t.Error("ArtistTrackTitles did not return an error on an unauthorized request")
+	}
+}
@@ -1,37 +1,295 @@
 package musicinfo
 
 import (
+	"cmp"
 	"fmt"
 	"regexp"
 	"slices"
 	"strings"
 	"time"
+	"unicode"
+	"unicode/utf8"
 
 	mb2 "go.uploadedlobster.com/musicbrainzws2"
 )
 
 var (
 	AltTrackTermGroups map[string][]string = map[string][]string{
-		"Latin":    {"acapella", "acoustic", "demo", "ext", "extended", "inst", "instrumental", "live", "mix", "piano", "radio", "remix", "remixed", "ver", "version"},
-		"Cyrillic": {"акустика", "версия", "инструментал", "радио"},
-	}
-	AltTrackExp *regexp.Regexp = regexp.MustCompile(
-		strings.Join([]string{
-			fmt.Sprintf(
-				`(?i)\s+[-‐-―].*\PL(?:%[1]v)(?:\PL|$).*|\s+\p{Ps}(?:.*\PL)?(?:%[1]v)(?:\PL.*)?\p{Pe}`,
-				strings.Join(slices.Concat(AltTrackTermGroups["Latin"], AltTrackTermGroups["Cyrillic"]), "|"),
-			),
-		}, "|"),
-	)
+		"English":  {"a cappella", "acapella", "acoustic", "alternate", "demo", "dub", "edit", "ext", "extended", "inst", "instrumental", "karaoke", "live", "mix", "orchestral", "piano", "radio", "rehearsal", "remaster", "remastered", "remix", "remixed", "session", "unplugged", "ver", "version"},
+		"French":   {"acoustique", "en concert", "en direct", "maquette", "remasterisé"},
+		"German":   {"akustik", "akustikversion", "fassung", "liveaufnahme", "liveversion", "mitschnitt", "neuaufnahme"},
+		"Japanese": {"アコースティック", "インスト", "インストゥルメンタル", "デモ", "バージョン", "ライブ", "リミックス"},
+		"Russian":  {"акустика", "акустическая", "версия", "демо", "живьём", "инструментал", "концерт", "концертная", "радио", "ремикс"},
+		"Spanish":  {"acústica", "acústico", "en directo", "en vivo", "maqueta", "remezcla", "versión"},
+	}
+	AltTrackExp      *regexp.Regexp      = altTrackExp(AltTrackTermGroups)
 	AlmostAltExp     *regexp.Regexp      = regexp.MustCompile(`\s+[-‐-―]\s+.*|\s*\p{Ps}.+\p{Pe}`)
 	NotAltTermGroups map[string][]string = map[string][]string{
-		"Latin": {"intro", "interlude"},
+		"English":  {"interlude", "intro", "outro", "overture", "prelude", "skit"},
+		"French":   {"interlude", "intro", "ouverture", "prélude"},
+		"German":   {"intro", "ouvertüre", "vorspiel", "zwischenspiel"},
+		"Japanese": {"イントロ", "インタールード"},
+		"Russian":  {"вступление", "интерлюдия", "интро"},
+		"Spanish":  {"interludio", "intro", "introducción", "obertura", "preludio"},
 	}
-	NotAltExp *regexp.Regexp = regexp.MustCompile(
-		fmt.Sprintf(`\s+[-‐-―]\s+(?:%[1]v)$|\s*\p{Ps}(?:%[1]v)\p{Pe}$`, strings.Join(NotAltTermGroups["Latin"], "|")),
-	)
+	NotAltExp       *regexp.Regexp = notAltExp(NotAltTermGroups)
+	FeaturingExp    *regexp.Regexp = regexp.MustCompile(`(?i)(?:^|\PL)(?:feat|ft|featuring)(?:\PL|$)`)
+	releaseIncludes []string       = []string{"release-groups", "media", "recordings", "artist-credits"}
+	workIncludes    []string       = []string{"recording-level-rels", "work-rels"}
+	// Separators between the songs of a medley titled as such, and of a title
+	// joining songs by slashes.
+	medleySeparatorExp *regexp.Regexp = regexp.MustCompile(`\s*[/;+]\s*|\s+[-‐-―]\s+`)
+	joinedSongsExp     *regexp.Regexp = regexp.MustCompile(`\s+/\s+`)
+)
+
+// AltKind is the kind of alternate version a track title is marked as.
+type AltKind string
+
+const (
+	AltLive         AltKind = "live"
+	AltRemix        AltKind = "remix"
+	AltAcoustic     AltKind = "acoustic"
+	AltInstrumental AltKind = "instrumental"
+	AltDemo         AltKind = "demo"
+	AltRadioEdit    AltKind = "radio-edit"
+	AltExtended     AltKind = "extended"
+	AltRemaster     AltKind = "remaster"
+	AltOther        AltKind = "other"
 )
 
+var (
+	AltKinds []AltKind = []AltKind{
+		AltLive, AltRemix, AltAcoustic, AltInstrumental, AltDemo, AltRadioEdit, AltExtended, AltRemaster, AltOther,
+	}
+	// Alternate terms classifying a version, with any term not found here,
+	// such as "version", classified as AltOther.
+	AltTermKinds map[string]AltKind = map[string]AltKind{
+		"akustik":        AltAcoustic,
+		"akustikversion": AltAcoustic,
+		"acoustic":       AltAcoustic,
+		"acoustique":     AltAcoustic,
+		"acústica":       AltAcoustic,
+		"acústico":       AltAcoustic,
+		"piano":          AltAcoustic,
+		"unplugged":      AltAcoustic,
+		"アコースティック":       AltAcoustic,
+		"акустика":       AltAcoustic,
+		"акустическая":   AltAcoustic,
+		"demo":           AltDemo,
+		"maqueta":        AltDemo,
+		"maquette":       AltDemo,
+		"デモ":             AltDemo,
+		"демо":           AltDemo,
+		"ext":            AltExtended,
+		"extended":       AltExtended,
+		"inst":           AltInstrumental,
+		"instrumental":   AltInstrumental,
+		"karaoke":        AltInstrumental,
+		"インスト":           AltInstrumental,
+		"インストゥルメンタル":     AltInstrumental,
+		"инструментал":   AltInstrumental,
+		"en concert":     AltLive,
+		"en direct":      AltLive,
+		"en directo":     AltLive,
+		"en vivo":        AltLive,
+		"live":           AltLive,
+		"liveaufnahme":   AltLive,
+		"liveversion":    AltLive,
+		"mitschnitt":     AltLive,
+		"session":        AltLive,
+		"ライブ":            AltLive,
+		"живьём":         AltLive,
+		"концерт":        AltLive,
+		"концертная":     AltLive,
+		"edit":           AltRadioEdit,
+		"radio":          AltRadioEdit,
+		"radio edit":     AltRadioEdit,
+		"радио":          AltRadioEdit,
+		"remaster":       AltRemaster,
+		"remastered":     AltRemaster,
+		"remasterisé":    AltRemaster,
+		"dub":            AltRemix,
+		"mix":            AltRemix,
+		"remezcla":       AltRemix,
+		"remix":          AltRemix,
+		"remixed":        AltRemix,
+		"リミックス":          AltRemix,
+		"ремикс":         AltRemix,
+	}
+)
+
+// AltTerms holds the expressions matching alternate and not-alternate suffixes
+// of track titles, built from term groups.
+type AltTerms struct {
+	AltExp    *regexp.Regexp
+	NotAltExp *regexp.Regexp
+}
+
+// DefaultAltTerms are the expressions of the default term groups.
+func DefaultAltTerms() AltTerms {
+	return AltTerms{AltExp: AltTrackExp, NotAltExp: NotAltExp}
+}
+
+// NewAltTerms builds the expressions of the default term groups extended by
+// the given ones, such as with languages from the configuration file. Terms are
+// added to the group of the same name, leaving the defaults unchanged.
+func NewAltTerms(alt, notAlt map[string][]string) AltTerms {
+	if len(alt) == 0 && len(notAlt) == 0 {
+		return DefaultAltTerms()
+	}
+	return AltTerms{
+		AltExp:    altTrackExp(mergeTermGroups(AltTrackTermGroups, alt)),
+		NotAltExp: notAltExp(mergeTermGroups(NotAltTermGroups, notAlt)),
+	}
+}
+
+// AltTrackKind classifies the title by the first alternate term with a kind
+// in its alternate suffix, returning an empty kind when the title isn't marked
+// as an alternate.
+func AltTrackKind(title string) AltKind {
+	return DefaultAltTerms().Kind(title)
+}
+
+// Kind classifies the title as AltTrackKind does, by these terms.
+func (a AltTerms) Kind(title string) AltKind {
+	suffix := a.AltExp.FindString(title)
+	if suffix == "" {
+		return ""
+	}
+	words := strings.FieldsFunc(strings.ToLower(suffix), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+	for i, word := range words {
+		// Terms of two words take precedence over either word alone.
+		if i+1 < len(words) {
+			if kind, ok := AltTermKinds[word+" "+words[i+1]]; ok {
+				return kind
+			}
+		}
+		if kind, ok := AltTermKinds[word]; ok {
+			return kind
+		}
+	}
+	return AltOther
+}
+
+// Returns a copy of the groups with the additions merged in.
+func mergeTermGroups(groups, additions map[string][]string) map[string][]string {
+	merged := make(map[string][]string, len(groups))
+	for name, terms := range groups {
+		merged[name] = slices.Clone(terms)
+	}
+	for name, terms := range additions {
+		for _, term := range terms {
+			if !slices.Contains(merged[name], term) {
+				merged[name] = append(merged[name], term)
+			}
+		}
+	}
+	return merged
+}
+
+// Joins the terms of every group into an alternation, longest first so that
+// a term is preferred over any it contains.
+func joinTerms(groups map[string][]string) string {
+	var terms []string
+	for _, group := range groups {
+		for _, term := range group {
+			term = strings.ToLower(strings.TrimSpace(term))
+			if term != "" && !slices.Contains(terms, term) {
+				terms = append(terms, term)
+			}
+		}
+	}
+	slices.SortFunc(terms, func(a, b string) int {
+		if c := cmp.Compare(len(b), len(a)); c != 0 {
+			return c
+		}
+		return cmp.Compare(a, b)
+	})
+	for i, term := range terms {
+		terms[i] = regexp.QuoteMeta(term)
+	}
+	return strings.Join(terms, "|")
+}
+
+func altTrackExp(groups map[string][]string) *regexp.Regexp {
+	return regexp.MustCompile(fmt.Sprintf(
+		`(?i)\s+[-‐-―].*\PL(?:%[1]v)(?:\PL|$).*|\s+\p{Ps}(?:.*\PL)?(?:%[1]v)(?:\PL.*)?\p{Pe}`,
+		joinTerms(groups),
+	))
+}
+
+func notAltExp(groups map[string][]string) *regexp.Regexp {
+	return regexp.MustCompile(fmt.Sprintf(
+		`(?i)\s+[-‐-―]\s+(?:%[1]v)$|\s*\p{Ps}(?:%[1]v)\p{Pe}$`,
+		joinTerms(groups),
+	))
+}
+
+// ReleaseIncludes returns what releases are retrieved along with, with works
+// adding their recordings' work relationships, as MedleyParts uses when available.
+func ReleaseIncludes(works bool) []string {
+	if works {
+		return slices.Concat(releaseIncludes, workIncludes)
+	}
+	return slices.Clone(releaseIncludes)
+}
+
+// MedleyParts returns the songs of a medley track: the works its recording is
+// a performance of, or else the songs named in its title, such as in
+// "Medley: A - B - C" or "A / B". Tracks of a single song have no parts.
+func MedleyParts(t mb2.Track) []string {
+	var works []string
+	for _, rel := range t.Recording.Relations {
+		if rel.TargetType == "work" && rel.Type == "performance" && rel.Work != nil && !slices.Contains(works, rel.Work.Title) {
+			works = append(works, rel.Work.Title)
+		}
+	}
+	if len(works) > 1 {
+		return works
+	}
+
+	var parts []string
+	title := strings.TrimSpace(t.Title)
+	if rest, ok := cutMedleyPrefix(title); ok {
+		parts = medleySeparatorExp.Split(rest, -1)
+	} else {
+		parts = joinedSongsExp.Split(title, -1)
+	}
+	var songs []string
+	for _, p := range parts {
+		if p = strings.TrimSpace(p); p != "" {
+			songs = append(songs, p)
+		}
+	}
+	if len(songs) > 1 {
+		return songs
+	}
+	return nil
+}
+
+// Cuts a leading "Medley", followed by a colon or dash or with the songs in
+// parentheses, from the title.
+func cutMedleyPrefix(title string) (string, bool) {
+	const prefix = "medley"
+	if len(title) <= len(prefix) || !strings.EqualFold(title[:len(prefix)], prefix) {
+		return "", false
+	}
+	rest := strings.TrimSpace(title[len(prefix):])
+	r, size := utf8.DecodeRuneInString(rest)
+	switch {
+	case r == ':' || unicode.Is(unicode.Pd, r):
+		return rest[size:], true
+	case unicode.Is(unicode.Ps, r):
+		last, lastSize := utf8.DecodeLastRuneInString(rest)
+		if unicode.Is(unicode.Pe, last) {
+			return rest[size : len(rest)-lastSize], true
+		}
+	}
+	return "", false
+}
+
 type MGClient struct {
 	MBClient   *mb2.Client
 	MBLimitter *time.Ticker
@@ -53,18 +311,110 @@ func (mgc MGClient) MBTick() {
 	<-mgc.MBLimitter.C
 }
 
-func ReleaseGroupsByArtist(client MGClient, artistID mb2.MBID, status string) ([]mb2.ReleaseGroup, error) {
+// CreditsArtist reports whether any of the artists is named in the credit.
+func CreditsArtist(credit mb2.ArtistCredit, artistIDs ...mb2.MBID) bool {
+	for _, name := range credit {
+		if slices.Contains(artistIDs, name.Artist.ID) {
+			return true
+		}
+	}
+	return false
+}
+
+// FeaturesArtist reports whether the artists are named in the credit only as
+// featured guests, following a join phrase such as "feat.".
+func FeaturesArtist(credit mb2.ArtistCredit, artistIDs ...mb2.MBID) bool {
+	var featuring, featured bool
+	for _, name := range credit {
+		if slices.Contains(artistIDs, name.Artist.ID) {
+			if !featuring {
+				return false
+			}
+			featured = true
+		}
+		if FeaturingExp.MatchString(name.JoinPhrase) {
+			featuring = true
+		}
+	}
+	return featured
+}
+
+func ReleaseGroupsByArtist(client MGClient, artistID mb2.MBID, status string, includes []string) ([]mb2.ReleaseGroup, error) {
+	rFilter := mb2.ReleaseFilter{ArtistMBID: artistID, Status: status, Includes: includes}
+	return releaseGroupsByFilter(client, rFilter)
+}
+
+// ReleaseGroupsByAppearance returns the release groups of releases carrying the
+// artist's recordings which aren't found on the known release groups, such as
+// soundtracks and compilations, along with the IDs of all the artist's recordings.
+func ReleaseGroupsByAppearance(client MGClient, artistID mb2.MBID, status string, includes []string, known []mb2.ReleaseGroup) ([]mb2.ReleaseGroup, map[mb2.MBID]bool, error) {
+	recordings := make(map[mb2.MBID]bool)
+	paginator := mb2.DefaultPaginator()
+	for {
+		client.MBTick()
+		result, err := client.MBClient.BrowseRecordings(mb2.RecordingFilter{ArtistMBID: artistID}, paginator)
+		if err != nil {
+			return nil, recordings, err
+		}
+		if len(result.Recordings) == 0 {
+			break
+		}
+		for _, rec := range result.Recordings {
+			recordings[rec.ID] = true
+		}
+		paginator.Offset = paginator.Offset + len(result.Recordings)
+	}
+
+	found := make(map[mb2.MBID]bool)
+	markFound := func(groups []mb2.ReleaseGroup) {
+		for _, rg := range groups {
+			for _, r := range rg.Releases {
+				for _, m := range r.Media {
+					for _, t := range m.Tracks {
+						found[t.Recording.ID] = true
+					}
+				}
+			}
+		}
+	}
+	markFound(known)
+
+	var groupLists [][]mb2.ReleaseGroup
+	for id := range recordings {
+		if found[id] {
+			continue
+		}
+		rFilter := mb2.ReleaseFilter{RecordingMBID: id, Status: status, Includes: includes}
+		groups, err := releaseGroupsByFilter(client, rFilter)
+		if err != nil {
+			return nil, recordings, err
+		}
+		// Releases carrying this recording may well carry others yet to be found.
+		markFound(groups)
+		found[id] = true
+		groupLists = append(groupLists, groups)
+	}
+	return MergeReleaseGroups(groupLists...), recordings, nil
+}
+
+// ReleaseGroupsByTrackArtist returns the release groups of releases where the
+// artist is credited on tracks, including releases credited to other artists.
+func ReleaseGroupsByTrackArtist(client MGClient, artistID mb2.MBID, status string, includes []string) ([]mb2.ReleaseGroup, error) {
+	rFilter := mb2.ReleaseFilter{TrackArtistMBID: artistID, Status: status, Includes: includes}
+	return releaseGroupsByFilter(client, rFilter)
+}
+
+func releaseGroupsByFilter(client MGClient, rFilter mb2.ReleaseFilter) ([]mb2.ReleaseGroup, error) {
 	rgByMBID := make(map[mb2.MBID]mb2.ReleaseGroup)
 	// Page through releases
 	paginator := mb2.DefaultPaginator()
-	rFilter := mb2.ReleaseFilter{ArtistMBID: artistID, Status: status, Includes: []string{"release-groups", "media", "recordings"}}
 	for {
 		client.MBTick()
 		result, err := client.MBClient.BrowseReleases(rFilter, paginator)
-		if err != nil || len(result.Releases) == 0 {
-			if err != nil {
-				fmt.Println(err)
-			}
+		if err != nil {
+			return nil, fmt.Errorf(`browsing releases at offset %v: %w`, paginator.Offset, err)
+		}
+		if len(result.Releases) == 0 {
 			break
 		}
 		for _, r := range result.Releases {
@@ -74,8 +424,8 @@ func ReleaseGroupsByArtist(client MGClient, artistID mb2.MBID, status string) ([
 			} else {
 				rg = *r.ReleaseGroup
 				rg.Releases = append(rg.Releases, r)
-				rgByMBID[r.ReleaseGroup.ID] = rg
 			}
+			rgByMBID[r.ReleaseGroup.ID] = rg
 		}
 		paginator.Offset = paginator.Offset + len(result.Releases)
 	}
@@ -83,6 +433,36 @@ func ReleaseGroupsByArtist(client MGClient, artistID mb2.MBID, status string) ([
 	for _, v := range rgByMBID {
 		groups = append(groups, v)
 	}
+	// Ordered by MBID, so that every run retrieving the same releases agrees.
+	slices.SortFunc(groups, func(a, b mb2.ReleaseGroup) int {
+		return cmp.Compare(a.ID, b.ID)
+	})
 
 	return groups, nil
 }
+
+// MergeReleaseGroups combines release groups retrieved separately, such that
+// each release group and each release within appears only once.
+func MergeReleaseGroups(groupLists ...[]mb2.ReleaseGroup) []mb2.ReleaseGroup {
+	var merged []mb2.ReleaseGroup
+	rgIndex := make(map[mb2.MBID]int)
+	seen := make(map[mb2.MBID]bool)
+	for _, groups := range groupLists {
+		for _, rg := range groups {
+			i, ok := rgIndex[rg.ID]
+			if !ok {
+				i = len(merged)
+				rgIndex[rg.ID] = i
+				merged = append(merged, rg)
+				merged[i].Releases = nil
+			}
+			for _, r := range rg.Releases {
+				if !seen[r.ID] {
+					seen[r.ID] = true
+					merged[i].Releases = append(merged[i].Releases, r)
+				}
+			}
+		}
+	}
+	return merged
+}
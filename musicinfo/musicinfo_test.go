@@ -2,7 +2,11 @@ package musicinfo
 
 import (
 	"fmt"
+	"regexp"
+	"slices"
 	"testing"
+	"unicode"
+	"unicode/utf8"
 
 	"go.uploadedlobster.com/musicbrainzws2"
 )
@@ -48,6 +52,146 @@ func TestAltTrackExp(t *testing.T) {
 	}
 }
 
+func TestAltTrackExpLanguages(t *testing.T) {
+	cases := []struct {
+		In   string
+		Want bool
+	}{
+		{`Song (Live at Wembley)`, true},
+		{`Song - 2011 Remaster`, true},
+		{`Song (A Cappella)`, true},
+		{`Alive (Reprise)`, false},
+		{`Chanson (En Direct)`, true},
+		{`Chanson - Version Acoustique`, true},
+		{`Chanson (Endroit)`, false},
+		{`Lied (Akustikversion)`, true},
+		{`Lied - Live-Mitschnitt 1994`, true},
+		{`Lied (Fassungslos)`, false},
+		{`曲 (ライブ)`, true},
+		{`曲 (ライブ・バージョン)`, true},
+		{`曲 (インストゥルメンタル)`, true},
+		{`曲 (ライブハウス)`, false},
+		{`Песня (Концертная версия)`, true},
+		{`Песня (Демо)`, true},
+		{`Песня (Радиоактивность)`, false},
+		{`Canción (En Vivo)`, true},
+		{`Canción - Versión Acústica`, true},
+		{`Canción (Remezcla)`, true},
+		{`Canción (Envivo)`, false},
+	}
+	for _, c := range cases {
+		if AltTrackExp.MatchString(c.In) != c.Want {
+			t.Errorf(`AltTrackExp returned %v on "%v", wanted %v`, !c.Want, c.In, c.Want)
+		}
+	}
+}
+
+func TestAltTrackKind(t *testing.T) {
+	cases := []struct {
+		In   string
+		Want AltKind
+	}{
+		{`Song`, ""},
+		{`Song (Interlude)`, ""},
+		{`Song (Live at Wembley)`, AltLive},
+		{`Song - Live Acoustic`, AltLive},
+		{`Song (Acoustic Live)`, AltAcoustic},
+		{`Song (Radio Edit)`, AltRadioEdit},
+		{`Song (Radio Mix)`, AltRadioEdit},
+		{`Song (Club Mix)`, AltRemix},
+		{`Song - 2011 Remaster`, AltRemaster},
+		{`Song (Extended Version)`, AltExtended},
+		{`Song (Instrumental)`, AltInstrumental},
+		{`Song (Demo)`, AltDemo},
+		{`Song (Alternate Version)`, AltOther},
+		{`Canción (En Vivo)`, AltLive},
+		{`Lied (Akustikversion)`, AltAcoustic},
+		{`曲 (リミックス)`, AltRemix},
+	}
+	for _, c := range cases {
+		if got := AltTrackKind(c.In); got != c.Want {
+			t.Errorf(`AltTrackKind("%v") = %q, wanted %q`, c.In, got, c.Want)
+		}
+	}
+}
+
+func TestAltTermKindsAreTerms(t *testing.T) {
+	for term := range AltTermKinds {
+		if !AltTrackExp.MatchString(fmt.Sprintf(`abc (%v)`, term)) {
+			t.Errorf(`AltTermKinds classifies "%v", which AltTrackExp doesn't match`, term)
+		}
+	}
+}
+
+func TestMedleyParts(t *testing.T) {
+	performance := func(titles ...string) []musicbrainzws2.Relationship {
+		var rels []musicbrainzws2.Relationship
+		for _, title := range titles {
+			rels = append(rels, musicbrainzws2.Relationship{Type: "performance", TargetType: "work", Work: &musicbrainzws2.Work{Title: title}})
+		}
+		return rels
+	}
+	cases := []struct {
+		Title     string
+		Relations []musicbrainzws2.Relationship
+		Want      []string
+	}{
+		{`Song`, nil, nil},
+		{`Song - Live`, nil, nil},
+		{`Either/Or`, nil, nil},
+		{`Medley`, nil, nil},
+		{`Medley (Live)`, nil, nil},
+		{`Medleys`, nil, nil},
+		{`Song A / Song B`, nil, []string{"Song A", "Song B"}},
+		{`Medley: X - Y - Z`, nil, []string{"X", "Y", "Z"}},
+		{`Medley - X / Y`, nil, []string{"X", "Y"}},
+		{`Medley (X / Y)`, nil, []string{"X", "Y"}},
+		{`Greatest Hits Medley`, performance("A", "B", "A"), []string{"A", "B"}},
+		{`Song`, performance("Song"), nil},
+	}
+	for _, c := range cases {
+		track := musicbrainzws2.Track{Title: c.Title, Recording: musicbrainzws2.Recording{Relations: c.Relations}}
+		if res := MedleyParts(track); !slices.Equal(res, c.Want) {
+			t.Errorf(`MedleyParts("%v") = %q, wanted %q`, c.Title, res, c.Want)
+		}
+	}
+}
+
+func TestNewAltTerms(t *testing.T) {
+	terms := NewAltTerms(
+		map[string][]string{"English": {"live", "bootleg"}, "Dutch": {"levend"}},
+		map[string][]string{"Portuguese": {"introdução"}},
+	)
+	cases := []struct {
+		Exp  *regexp.Regexp
+		In   string
+		Want bool
+	}{
+		{terms.AltExp, `Song (Live)`, true},
+		{terms.AltExp, `Song (Bootleg)`, true},
+		{terms.AltExp, `Lied (Levend)`, true},
+		{terms.AltExp, `Song (Demo)`, true},
+		{terms.AltExp, `Song (Reprise)`, false},
+		{terms.NotAltExp, `Song (intro)`, true},
+		{terms.NotAltExp, `Canção (introdução)`, true},
+		{terms.NotAltExp, `Song (Intro)`, true},
+		{terms.NotAltExp, `Canção (Introdução)`, true},
+		{AltTrackExp, `Song (Bootleg)`, false},
+		{NotAltExp, `Canção (introdução)`, false},
+	}
+	for _, c := range cases {
+		if c.Exp.MatchString(c.In) != c.Want {
+			t.Errorf(`%v returned %v on "%v", wanted %v`, c.Exp, !c.Want, c.In, c.Want)
+		}
+	}
+	if slices.Contains(AltTrackTermGroups["English"], "bootleg") {
+		t.Errorf(`NewAltTerms changed the default term groups, %v`, AltTrackTermGroups["English"])
+	}
+	if kind := terms.Kind(`Song (Bootleg Live)`); kind != AltLive {
+		t.Errorf(`Kind("Song (Bootleg Live)") = %q, wanted %q`, kind, AltLive)
+	}
+}
+
 func TestAlmostAltExp(t *testing.T) {
 	cases := []struct {
 		In   string
@@ -96,10 +240,14 @@ func TestNotAltExp(t *testing.T) {
 	}
 	for _, group := range NotAltTermGroups {
 		for _, term := range group {
-			for _, c := range cases {
-				m := fmt.Sprintf(c.Format, term)
-				if NotAltExp.MatchString(m) != c.Want {
-					t.Errorf(`NotAltExp returned %v on "%v", wanted %v`, !c.Want, m, c.Want)
+			// Terms are matched regardless of case, as in "Song (Interlude)".
+			r, size := utf8.DecodeRuneInString(term)
+			for _, term := range []string{term, string(unicode.ToUpper(r)) + term[size:]} {
+				for _, c := range cases {
+					m := fmt.Sprintf(c.Format, term)
+					if NotAltExp.MatchString(m) != c.Want {
+						t.Errorf(`NotAltExp returned %v on "%v", wanted %v`, !c.Want, m, c.Want)
+					}
 				}
 			}
 		}
@@ -110,7 +258,7 @@ func TestReleaseGroupsByArtistNotEmpty(t *testing.T) {
 	client, stop := NewMGClient()
 	defer stop()
 	mbid := musicbrainzws2.MBID("7e870dd5-2667-454b-9fcf-a132dd8071f1")
-	groups, err := ReleaseGroupsByArtist(client, mbid, "")
+	groups, err := ReleaseGroupsByArtist(client, mbid, "", ReleaseIncludes(false))
 	if err != nil {
 		t.Fatalf(`ReleaseGroupsByArtist(client, %v) returned error, %q`, mbid, err)
 	}
@@ -138,7 +286,7 @@ func TestReleaseGroupsByArtistPagination(t *testing.T) {
 	client, stop := NewMGClient()
 	defer stop()
 	mbid := musicbrainzws2.MBID("a1ed5e33-22ff-4e7d-a457-42f4309e135f")
-	groups, err := ReleaseGroupsByArtist(client, mbid, "")
+	groups, err := ReleaseGroupsByArtist(client, mbid, "", ReleaseIncludes(false))
 	if err != nil {
 		t.Fatalf(`ReleaseGroupsByArtist(client, %v) returned error, %q`, mbid, err)
 	}
@@ -146,3 +294,56 @@ func TestReleaseGroupsByArtistPagination(t *testing.T) {
 		t.Errorf(`ReleaseGroupsByArtist(client, %v) returned %v release groups`, mbid, len(groups))
 	}
 }
+
+func TestMergeReleaseGroups(t *testing.T) {
+	a := []musicbrainzws2.ReleaseGroup{
+		{ID: "split", Releases: []musicbrainzws2.Release{{ID: "split-cd"}}},
+		{ID: "album", Releases: []musicbrainzws2.Release{{ID: "album-cd"}, {ID: "album-lp"}}},
+	}
+	b := []musicbrainzws2.ReleaseGroup{
+		{ID: "split", Releases: []musicbrainzws2.Release{{ID: "split-cd"}, {ID: "split-lp"}}},
+		{ID: "other", Releases: []musicbrainzws2.Release{{ID: "other-cd"}}},
+	}
+	want := map[musicbrainzws2.MBID]int{"split": 2, "album": 2, "other": 1}
+	merged := MergeReleaseGroups(a, b)
+	if len(merged) != len(want) {
+		t.Fatalf(`MergeReleaseGroups(%v, %v) = %v, wanted %v release groups`, a, b, merged, len(want))
+	}
+	for _, rg := range merged {
+		if len(rg.Releases) != want[rg.ID] {
+			t.Errorf(`MergeReleaseGroups(%v, %v) returned %v releases in %v, wanted %v`, a, b, len(rg.Releases), rg.ID, want[rg.ID])
+		}
+	}
+	if len(a[0].Releases) != 1 {
+		t.Errorf(`MergeReleaseGroups modified its input %v`, a)
+	}
+}
+
+func TestArtistCredits(t *testing.T) {
+	a := musicbrainzws2.Artist{ID: "a"}
+	b := musicbrainzws2.Artist{ID: "b"}
+	c := musicbrainzws2.Artist{ID: "c"}
+	cases := []struct {
+		Credit   musicbrainzws2.ArtistCredit
+		Credits  bool
+		Features bool
+	}{
+		{musicbrainzws2.ArtistCredit{{Artist: a}}, true, false},
+		{musicbrainzws2.ArtistCredit{{Artist: b}}, false, false},
+		{musicbrainzws2.ArtistCredit{{Artist: a, JoinPhrase: " & "}, {Artist: b}}, true, false},
+		{musicbrainzws2.ArtistCredit{{Artist: b, JoinPhrase: " & "}, {Artist: a}}, true, false},
+		{musicbrainzws2.ArtistCredit{{Artist: a, JoinPhrase: " feat. "}, {Artist: b}}, true, false},
+		{musicbrainzws2.ArtistCredit{{Artist: b, JoinPhrase: " feat. "}, {Artist: a}}, true, true},
+		{musicbrainzws2.ArtistCredit{{Artist: b, JoinPhrase: " ft. "}, {Artist: c, JoinPhrase: " & "}, {Artist: a}}, true, true},
+		{musicbrainzws2.ArtistCredit{{Artist: b, JoinPhrase: " featuring "}, {Artist: a}}, true, true},
+		{musicbrainzws2.ArtistCredit{{Artist: b, JoinPhrase: " after "}, {Artist: a}}, true, false},
+	}
+	for _, c := range cases {
+		if res := CreditsArtist(c.Credit, a.ID); res != c.Credits {
+			t.Errorf(`CreditsArtist(%v, %v) = %v, wanted %v`, c.Credit, a.ID, res, c.Credits)
+		}
+		if res := FeaturesArtist(c.Credit, a.ID); res != c.Features {
+			t.Errorf(`FeaturesArtist(%v, %v) = %v, wanted %v`, c.Credit, a.ID, res, c.Features)
+		}
+	}
+}
@@ -0,0 +1,301 @@
+package titlematch
+
+import (
+	"cmp"
+	"math"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+const (
+	gramSize int = 2
+	// Leeway for rounding in similarities computed by the metrics.
+	epsilon float64 = 1e-9
+)
+
+// Pair holds the indexes of two titles, I less than J.
+type Pair struct {
+	I, J int
+}
+
+func comparePairs(a, b Pair) int {
+	if c := cmp.Compare(a.I, b.I); c != 0 {
+		return c
+	}
+	return cmp.Compare(a.J, b.J)
+}
+
+// AlikePairs returns the pairs of titles more similar than the threshold under
+// the named metric, in order. Only candidate pairs are scored, in parallel.
+func AlikePairs(titles []string, name string, threshold float64) ([]Pair, error) {
+	if _, err := NewMetric(name); err != nil {
+		return nil, err
+	}
+	candidates := Candidates(titles, name, threshold)
+	workers := runtime.GOMAXPROCS(0)
+	chunk := (len(candidates) + workers - 1) / workers
+	results := make([][]Pair, workers)
+	var wg sync.WaitGroup
+	for w := 0; w < workers && w*chunk < len(candidates); w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			metric, _ := NewMetric(name)
+			for _, p := range candidates[w*chunk : min((w+1)*chunk, len(candidates))] {
+				if Similarity(titles[p.I], titles[p.J], metric) > threshold {
+					results[w] = append(results[w], p)
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+	return slices.Concat(results...), nil
+}
+
+// Candidates returns the pairs of titles which may be more similar than the
+// threshold under the named metric, in order. Pairs are only left out when
+// their similarity is bounded at or below the threshold, so every pair more
+// similar is a candidate.
+func Candidates(titles []string, name string, threshold float64) []Pair {
+	var pairs []Pair
+	switch name {
+	case JaroWinkler:
+		pairs = jaroWinklerCandidates(titles, threshold)
+	case TokenSet:
+		pairs = tokenSetCandidates(titles, threshold)
+	default:
+		pairs = levenshteinCandidates(titles, threshold)
+	}
+	slices.SortFunc(pairs, comparePairs)
+	return slices.Compact(pairs)
+}
+
+// The most edits that two strings, the longer of the given length, may be
+// apart and still be more similar than the threshold. Rounded up, to err on
+// the side of a candidate.
+func maxEdits(length int, threshold float64) int {
+	return int(math.Floor((1-threshold)*float64(length) + epsilon))
+}
+
+// Strings within k edits of each other share at least this many q-grams,
+// where length is that of the longer string.
+func minCommonGrams(length int, k int) int {
+	return length - gramSize + 1 - k*gramSize
+}
+
+func grams(s string) map[string]int {
+	runes := []rune(s)
+	counts := make(map[string]int)
+	for i := 0; i+gramSize <= len(runes); i++ {
+		counts[string(runes[i:i+gramSize])]++
+	}
+	return counts
+}
+
+type gramCount struct {
+	Title int
+	Count int
+}
+
+// Pairs strings within the edit distance the threshold allows, by their
+// lengths and the q-grams they share. Pairs of strings too short to be
+// required to share any q-gram are paired on length alone.
+func levenshteinCandidates(titles []string, threshold float64) []Pair {
+	lengths := make([]int, len(titles))
+	index := make(map[string][]gramCount)
+	for i, t := range titles {
+		lengths[i] = utf8.RuneCountInString(t)
+		for g, count := range grams(t) {
+			index[g] = append(index[g], gramCount{i, count})
+		}
+	}
+	withinLength := func(i, j int) bool {
+		long := max(lengths[i], lengths[j])
+		return abs(lengths[i]-lengths[j]) <= maxEdits(long, threshold)
+	}
+
+	var pairs []Pair
+	common := make(map[int]int)
+	for i, t := range titles {
+		clear(common)
+		for g, count := range grams(t) {
+			for _, gc := range index[g] {
+				if gc.Title > i {
+					common[gc.Title] += min(count, gc.Count)
+				}
+			}
+		}
+		for j, shared := range common {
+			long := max(lengths[i], lengths[j])
+			if withinLength(i, j) && shared >= minCommonGrams(long, maxEdits(long, threshold)) {
+				pairs = append(pairs, Pair{i, j})
+			}
+		}
+	}
+
+	// Strings are short when they needn't share any q-grams with those no
+	// longer than them.
+	order := make([]int, len(titles))
+	for i := range order {
+		order[i] = i
+	}
+	slices.SortFunc(order, func(a, b int) int {
+		return cmp.Compare(lengths[a], lengths[b])
+	})
+	for n, j := range order {
+		if minCommonGrams(lengths[j], maxEdits(lengths[j], threshold)) > 0 {
+			continue
+		}
+		for _, i := range order[:n] {
+			if withinLength(i, j) {
+				pairs = append(pairs, Pair{min(i, j), max(i, j)})
+			}
+		}
+		// Strings of equal length further along in the order.
+		for _, i := range order[n+1:] {
+			if lengths[i] != lengths[j] {
+				break
+			}
+			pairs = append(pairs, Pair{min(i, j), max(i, j)})
+		}
+	}
+	return pairs
+}
+
+// Pairs strings whose sorted words are alike by Levenshtein, as that is how
+// strings without shared words are compared, along with strings sharing words
+// whose similarity may exceed the threshold. Of the words in common and those
+// of either string alone, the common words compare to either string by the
+// ratio of their lengths, and the strings compare to each other by the edit
+// distance of their own words, bounded by the runes those have in common.
+func tokenSetCandidates(titles []string, threshold float64) []Pair {
+	tokens := make([][]string, len(titles))
+	sorted := make([]string, len(titles))
+	index := make(map[string][]int)
+	for i, t := range titles {
+		tokens[i] = tokenSet(t)
+		sorted[i] = strings.Join(tokens[i], " ")
+		for _, token := range tokens[i] {
+			index[token] = append(index[token], i)
+		}
+	}
+	pairs := levenshteinCandidates(sorted, threshold)
+	sharing := make(map[int]bool)
+	for i := range titles {
+		clear(sharing)
+		for _, token := range tokens[i] {
+			for _, j := range index[token] {
+				if j > i {
+					sharing[j] = true
+				}
+			}
+		}
+		for j := range sharing {
+			if tokenSetBound(tokens[i], tokens[j]) > threshold-epsilon {
+				pairs = append(pairs, Pair{i, j})
+			}
+		}
+	}
+	return pairs
+}
+
+// Bounds the token set similarity of strings of the sorted, distinct words.
+func tokenSetBound(a, b []string) float64 {
+	var common, onlyA, onlyB []string
+	for _, t := range a {
+		if _, ok := slices.BinarySearch(b, t); ok {
+			common = append(common, t)
+		} else {
+			onlyA = append(onlyA, t)
+		}
+	}
+	for _, t := range b {
+		if _, ok := slices.BinarySearch(a, t); !ok {
+			onlyB = append(onlyB, t)
+		}
+	}
+	restA, restB := strings.Join(onlyA, " "), strings.Join(onlyB, " ")
+	lenCommon := utf8.RuneCountInString(strings.Join(common, " "))
+	lenRestA, lenRestB := utf8.RuneCountInString(restA), utf8.RuneCountInString(restB)
+	lenA, lenB := lenCommon+min(lenRestA, 1)+lenRestA, lenCommon+min(lenRestB, 1)+lenRestB
+	if lenA == 0 || lenB == 0 {
+		return 1
+	}
+
+	runes := make(map[rune]int)
+	for _, r := range restA {
+		runes[r]++
+	}
+	var shared int
+	for _, r := range restB {
+		if runes[r] > 0 {
+			runes[r]--
+			shared++
+		}
+	}
+	edits := max(lenRestA, lenRestB) - shared
+	return max(
+		float64(lenCommon)/float64(lenA),
+		float64(lenCommon)/float64(lenB),
+		1-float64(edits)/float64(max(lenA, lenB)),
+	)
+}
+
+// Pairs strings whose Jaro-Winkler similarity may exceed the threshold, as
+// bounded by their lengths and the runes they have in common. The prefix
+// bonus raises a Jaro similarity j to at most 0.4 + 0.6j, and j is at most a
+// third of the sum of the shares of each string's runes in common and one.
+func jaroWinklerCandidates(titles []string, threshold float64) []Pair {
+	minJaro := (threshold - 0.4) / 0.6
+	lengths := make([]int, len(titles))
+	runeCounts := make([]map[rune]int, len(titles))
+	order := make([]int, len(titles))
+	for i, t := range titles {
+		lengths[i] = utf8.RuneCountInString(t)
+		runeCounts[i] = make(map[rune]int)
+		for _, r := range t {
+			runeCounts[i][r]++
+		}
+		order[i] = i
+	}
+	slices.SortFunc(order, func(a, b int) int {
+		return cmp.Compare(lengths[a], lengths[b])
+	})
+	bound := func(common, a, b int) float64 {
+		if a == 0 || b == 0 {
+			if a == b {
+				return 1
+			}
+			return 0
+		}
+		return (float64(common)/float64(a) + float64(common)/float64(b) + 1) / 3
+	}
+
+	var pairs []Pair
+	for n, i := range order {
+		for _, j := range order[n+1:] {
+			// Lengths only grow along the order, loosening the bound no further.
+			if bound(lengths[i], lengths[i], lengths[j]) <= minJaro-epsilon {
+				break
+			}
+			var common int
+			for r, count := range runeCounts[i] {
+				common += min(count, runeCounts[j][r])
+			}
+			if bound(common, lengths[i], lengths[j]) > minJaro-epsilon {
+				pairs = append(pairs, Pair{min(i, j), max(i, j)})
+			}
+		}
+	}
+	return pairs
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,97 @@
+package titlematch
+
+import (
+	"math/rand"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func randomTitles(r *rand.Rand, n int) []string {
+	words := []string{"a", "i", "love", "lover", "loved", "you", "your", "me", "night", "nights", "light", "day", "days", "away", "a way", "2", "3", "live", "remix", "song", "long", "gone", "done", "sun", "run", "на", "ночь"}
+	titles := []string{"", "a", "ab", "ba"}
+	for len(titles) < n {
+		var title []string
+		for w := r.Intn(5); w >= 0; w-- {
+			title = append(title, words[r.Intn(len(words))])
+		}
+		titles = append(titles, strings.Join(title, " "))
+	}
+	// Near duplicates, differing by a rune or two.
+	for i := 0; i < n/4; i++ {
+		runes := []rune(titles[r.Intn(len(titles))])
+		if len(runes) == 0 {
+			continue
+		}
+		runes[r.Intn(len(runes))] = 'x'
+		if r.Intn(2) == 0 {
+			runes = runes[1:]
+		}
+		titles = append(titles, string(runes))
+	}
+	return titles
+}
+
+func bruteForcePairs(titles []string, name string, threshold float64) []Pair {
+	metric, _ := NewMetric(name)
+	var pairs []Pair
+	for i := range titles {
+		for j := i + 1; j < len(titles); j++ {
+			if Similarity(titles[i], titles[j], metric) > threshold {
+				pairs = append(pairs, Pair{i, j})
+			}
+		}
+	}
+	return pairs
+}
+
+func TestAlikePairs(t *testing.T) {
+	r := rand.New(rand.NewSource(42))
+	titles := randomTitles(r, 400)
+	for _, name := range Metrics {
+		for _, threshold := range []float64{0.3, 0.5, 0.6, 0.75, 0.85, 0.9} {
+			want := bruteForcePairs(titles, name, threshold)
+			res, err := AlikePairs(titles, name, threshold)
+			if err != nil {
+				t.Fatalf(`AlikePairs returned error: %v`, err)
+			}
+			if !slices.Equal(res, want) {
+				t.Errorf(`AlikePairs with %v above %v found %v pairs, wanted the %v found by brute force`, name, threshold, len(res), len(want))
+			}
+			if candidates := Candidates(titles, name, threshold); len(candidates) < len(want) {
+				t.Errorf(`Candidates with %v above %v returned fewer pairs than are alike`, name, threshold)
+			}
+		}
+	}
+}
+
+func TestCandidatesPrune(t *testing.T) {
+	r := rand.New(rand.NewSource(7))
+	titles := randomTitles(r, 1000)
+	all := len(titles) * (len(titles) - 1) / 2
+	for _, name := range []string{Levenshtein, TokenSet} {
+		if n := len(Candidates(titles, name, DefaultThresholds[name])); n > all/2 {
+			t.Errorf(`Candidates with %v returned %v of %v pairs, wanted most pruned`, name, n, all)
+		}
+	}
+}
+
+func TestTokenSetCandidatesStopWords(t *testing.T) {
+	// Titles sharing only "the", with words alike when of the same letter.
+	var titles []string
+	for n := 3; n < 7; n++ {
+		for r := 'a'; r <= 'z'; r++ {
+			titles = append(titles, "the "+strings.Repeat(string(r), n))
+		}
+	}
+	want := bruteForcePairs(titles, TokenSet, DefaultThresholds[TokenSet])
+	candidates := Candidates(titles, TokenSet, DefaultThresholds[TokenSet])
+	for _, p := range want {
+		if _, ok := slices.BinarySearchFunc(candidates, p, comparePairs); !ok {
+			t.Errorf(`Candidates left out the alike pair %q and %q`, titles[p.I], titles[p.J])
+		}
+	}
+	if all := len(titles) * (len(titles) - 1) / 2; len(candidates) > all/10 {
+		t.Errorf(`Candidates returned %v of %v pairs sharing a stop word, wanted most pruned`, len(candidates), all)
+	}
+}
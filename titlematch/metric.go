@@ -0,0 +1,91 @@
+package titlematch
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/adrg/strutil"
+	"github.com/adrg/strutil/metrics"
+)
+
+const (
+	Levenshtein string = "levenshtein"
+	JaroWinkler string = "jaro-winkler"
+	TokenSet    string = "token-set"
+)
+
+var (
+	Metrics []string = []string{Levenshtein, JaroWinkler, TokenSet}
+	// Similarity above which titles are taken as alike, by metric.
+	DefaultThresholds map[string]float64 = map[string]float64{
+		Levenshtein: 0.6,
+		JaroWinkler: 0.85,
+		TokenSet:    0.8,
+	}
+)
+
+// NewMetric returns the named string metric, which is safe for concurrent use.
+func NewMetric(name string) (strutil.StringMetric, error) {
+	switch name {
+	case Levenshtein:
+		return metrics.NewLevenshtein(), nil
+	case JaroWinkler:
+		return metrics.NewJaroWinkler(), nil
+	case TokenSet:
+		return TokenSetMetric{Base: metrics.NewLevenshtein()}, nil
+	}
+	return nil, fmt.Errorf(`unknown similarity metric %q, expected one of %v`, name, Metrics)
+}
+
+// Similarity compares normalized titles with the metric, where titles
+// normalizing to the same form are always identical.
+func Similarity(a, b string, metric strutil.StringMetric) float64 {
+	if a == b {
+		return 1
+	}
+	if a == "" || b == "" {
+		return 0
+	}
+	return strutil.Similarity(a, b, metric)
+}
+
+// TokenSetMetric compares the words two strings have in common against the
+// words of each, such that word order and words found in only one of the
+// strings matter less.
+type TokenSetMetric struct {
+	// Base compares the strings built from the words.
+	Base strutil.StringMetric
+}
+
+func (m TokenSetMetric) Compare(a, b string) float64 {
+	tokensA, tokensB := tokenSet(a), tokenSet(b)
+	var common, onlyA, onlyB []string
+	for _, t := range tokensA {
+		if slices.Contains(tokensB, t) {
+			common = append(common, t)
+		} else {
+			onlyA = append(onlyA, t)
+		}
+	}
+	for _, t := range tokensB {
+		if !slices.Contains(tokensA, t) {
+			onlyB = append(onlyB, t)
+		}
+	}
+	intersection := strings.Join(common, " ")
+	withA := strings.TrimSpace(intersection + " " + strings.Join(onlyA, " "))
+	withB := strings.TrimSpace(intersection + " " + strings.Join(onlyB, " "))
+	return max(
+		Similarity(intersection, withA, m.Base),
+		Similarity(intersection, withB, m.Base),
+		Similarity(withA, withB, m.Base),
+	)
+}
+
+// Returns the sorted, distinct words of the string.
+func tokenSet(s string) []string {
+	tokens := strings.Fields(s)
+	slices.Sort(tokens)
+	return slices.Compact(tokens)
+}
@@ -0,0 +1,47 @@
+package titlematch
+
+import "testing"
+
+func TestNewMetric(t *testing.T) {
+	for _, name := range Metrics {
+		metric, err := NewMetric(name)
+		if err != nil {
+			t.Errorf(`NewMetric(%q) returned error: %v`, name, err)
+			continue
+		}
+		if sim := Similarity("song", "song", metric); sim != 1 {
+			t.Errorf(`%v similarity of identical titles = %v, wanted 1`, name, sim)
+		}
+		if _, ok := DefaultThresholds[name]; !ok {
+			t.Errorf(`%v has no default threshold`, name)
+		}
+	}
+	if _, err := NewMetric("soundex"); err == nil {
+		t.Error(`NewMetric("soundex") did not return an error`)
+	}
+}
+
+func TestSimilarity(t *testing.T) {
+	cases := []struct {
+		Metric string
+		A, B   string
+		Alike  bool
+	}{
+		{Levenshtein, "hello goodbye", "hello goodby", true},
+		{Levenshtein, "hello goodbye", "yellow submarine", false},
+		{Levenshtein, "", "", true},
+		{Levenshtein, "", "song", false},
+		{JaroWinkler, "hello goodbye", "hello goodby", true},
+		{JaroWinkler, "hello goodbye", "yellow submarine", false},
+		{TokenSet, "goodbye hello", "hello goodbye", true},
+		{TokenSet, "hello goodbye", "hello goodbye reprise", true},
+		{TokenSet, "hello goodbye", "yellow submarine", false},
+	}
+	for _, c := range cases {
+		metric, _ := NewMetric(c.Metric)
+		sim := Similarity(c.A, c.B, metric)
+		if (sim > DefaultThresholds[c.Metric]) != c.Alike {
+			t.Errorf(`%v similarity of "%v" and "%v" = %v, wanted alike %v`, c.Metric, c.A, c.B, sim, c.Alike)
+		}
+	}
+}
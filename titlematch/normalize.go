@@ -0,0 +1,106 @@
+// Package titlematch normalizes track titles and measures their similarity,
+// for recognizing the same song titled differently across releases.
+package titlematch
+
+import (
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+var (
+	featuringExp *regexp.Regexp = regexp.MustCompile(
+		`\s*[(\[]\s*(?:feat|ft|featuring)\b\.?[^)\]]*[)\]]|\s+(?:feat|ft|featuring)\.?\s.*$`,
+	)
+	romanNumerals map[string]int = romanNumeralValues(39)
+	// Words a Roman numeral may follow anywhere in a title.
+	numberedWords []string = []string{"part", "pt", "chapter", "ch", "vol", "volume", "no", "number", "act", "book"}
+	// Transliterations of lowercase Cyrillic and Greek letters, after their
+	// diacritics have been folded.
+	transliterations map[rune]string = map[rune]string{
+		'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ж': "zh", 'з': "z",
+		'и': "i", 'к': "k", 'л': "l", 'м': "m", 'н': "n", 'о': "o", 'п': "p", 'р': "r",
+		'с': "s", 'т': "t", 'у': "u", 'ф': "f", 'х': "kh", 'ц': "ts", 'ч': "ch", 'ш': "sh",
+		'щ': "shch", 'ъ': "", 'ы': "y", 'ь': "", 'э': "e", 'ю': "yu", 'я': "ya", 'і': "i",
+		'є': "ye", 'ґ': "g",
+		'α': "a", 'β': "v", 'γ': "g", 'δ': "d", 'ε': "e", 'ζ': "z", 'η': "i", 'θ': "th",
+		'ι': "i", 'κ': "k", 'λ': "l", 'μ': "m", 'ν': "n", 'ξ': "x", 'ο': "o", 'π': "p",
+		'ρ': "r", 'σ': "s", 'ς': "s", 'τ': "t", 'υ': "y", 'φ': "f", 'χ': "ch", 'ψ': "ps",
+		'ω': "o",
+	}
+)
+
+// Normalize reduces a title to a canonical form for comparison. Compatibility
+// characters are unified (NFKC), letters are lowercased, diacritics folded, and
+// Cyrillic and Greek transliterated. Featured artists are dropped, "&" becomes
+// "and", apostrophes are removed and other punctuation separates words, and
+// Roman numerals become Arabic numerals where they number a part or end the
+// title, such as in "Part II" or "Symphony IV".
+func Normalize(title string) string {
+	title = norm.NFKC.String(title)
+	title = strings.ToLower(title)
+	title = foldDiacritics(title)
+	title = transliterate(title)
+	title = featuringExp.ReplaceAllLiteralString(title, "")
+	title = strings.ReplaceAll(title, "&", " and ")
+	title = strings.Map(func(r rune) rune {
+		switch {
+		case r == '\'' || r == '’' || r == '`' || r == '´' || r == 'ʼ':
+			return -1
+		case unicode.IsPunct(r) || unicode.IsSymbol(r):
+			return ' '
+		}
+		return r
+	}, title)
+	words := strings.Fields(title)
+	for i, word := range words {
+		n, ok := romanNumerals[word]
+		if !ok {
+			continue
+		}
+		// A single letter ending the title is more likely a word, as in "Me and I".
+		numbered := i > 0 && slices.Contains(numberedWords, words[i-1])
+		if numbered || i == len(words)-1 && len(word) > 1 {
+			words[i] = strconv.Itoa(n)
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+func foldDiacritics(s string) string {
+	folded := strings.Map(func(r rune) rune {
+		if unicode.Is(unicode.Mn, r) {
+			return -1
+		}
+		return r
+	}, norm.NFD.String(s))
+	return norm.NFC.String(folded)
+}
+
+func transliterate(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if t, ok := transliterations[r]; ok {
+			b.WriteString(t)
+		} else {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// Maps lowercase Roman numerals to their values, up to max. Numerals written
+// with L, C, D, or M are left out, as they are easily mistaken for words.
+func romanNumeralValues(max int) map[string]int {
+	numerals := make(map[string]int)
+	for n := 1; n <= max; n++ {
+		numeral := strings.Repeat("x", n/10)
+		numeral += []string{"", "i", "ii", "iii", "iv", "v", "vi", "vii", "viii", "ix"}[n%10]
+		numerals[numeral] = n
+	}
+	return numerals
+}
@@ -0,0 +1,42 @@
+package titlematch
+
+import "testing"
+
+func TestNormalize(t *testing.T) {
+	cases := []struct {
+		In   string
+		Want string
+	}{
+		{`Don’t Stop Me Now`, `dont stop me now`},
+		{`Don't Stop Me Now`, `dont stop me now`},
+		{`Rock & Roll`, `rock and roll`},
+		{`Hello, Goodbye`, `hello goodbye`},
+		{`Hello   Goodbye `, `hello goodbye`},
+		{`Café Society`, `cafe society`},
+		{`Ｆｕｌｌｗｉｄｔｈ`, `fullwidth`},
+		{`ﬁnale`, `finale`},
+		{`Song (feat. Someone Else)`, `song`},
+		{`Song [ft. Someone]`, `song`},
+		{`Song feat. Someone`, `song`},
+		{`Song Featuring Someone`, `song`},
+		{`Left Feature`, `left feature`},
+		{`Part II`, `part 2`},
+		{`Chapter XIV: The End`, `chapter 14 the end`},
+		{`Mix`, `mix`},
+		{`I Want You`, `i want you`},
+		{`Me and I`, `me and i`},
+		{`Vol. V`, `vol 5`},
+		{`Symphony IV`, `symphony 4`},
+		{`X Marks the Spot`, `x marks the spot`},
+		{`Кино`, `kino`},
+		{`Звезда по имени Солнце`, `zvezda po imeni solntse`},
+		{`Ἀθήνα`, `athina`},
+		{`Σίσυφος`, `sisyfos`},
+		{`Song - Live / Acoustic`, `song live acoustic`},
+	}
+	for _, c := range cases {
+		if res := Normalize(c.In); res != c.Want {
+			t.Errorf(`Normalize("%v") = "%v", wanted "%v"`, c.In, res, c.Want)
+		}
+	}
+}